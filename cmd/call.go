@@ -5,7 +5,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -23,6 +27,45 @@ import (
 // From var
 var From string
 
+// Body var
+var Body string
+
+// Out var
+var Out string
+
+// Resume var
+var Resume bool
+
+// Timing var
+var Timing bool
+
+// Dump var
+var Dump bool
+
+// NoFollow var
+var NoFollow bool
+
+// MaxRedirects var
+var MaxRedirects int
+
+// KeepAuth var
+var KeepAuth bool
+
+// Timeout var
+var Timeout string
+
+// Send var
+var Send []string
+
+// Replies var
+var Replies int
+
+// Batch var
+var Batch bool
+
+// Events var
+var Events int
+
 var callCmd = &cobra.Command{
 	Use:   "call",
 	Short: "Interact with one of the configured services",
@@ -55,59 +98,95 @@ var callCmd = &cobra.Command{
 			return
 		}
 
-		files := make(map[string]util.File)
+		body := ""
+		prompt := module.Prompt{}
 
-		if From == "" || !util.FileExists(From) {
-			files, err = util.ListFiles(util.EnsureTrailingSlash(conf.Services.Directory))
-		} else {
-			files[filepath.Base(From)] = util.File{
-				Path: From,
-				Name: filepath.Base(From),
+		if Body != "" {
+			body, err = readBody(Body)
+
+			if err != nil {
+				fmt.Printf("Error while reading body %s: %s", Body, err.Error())
+				return
+			}
+		}
+
+		// Since stdin is consumed by the body, use the terminal for prompts
+		if Body == "-" {
+			tty, err := os.Open("/dev/tty")
+
+			if err != nil {
+				fmt.Printf("Error while opening terminal: %s", err.Error())
+				return
 			}
+
+			defer tty.Close()
+
+			prompt.Stdin = tty
 		}
 
+		project, err := loadProject(conf)
+
 		if err != nil {
-			fmt.Printf(
-				"Error while listing services under %s: %s",
-				util.EnsureTrailingSlash(conf.Services.Directory),
-				err.Error(),
-			)
+			fmt.Printf("Error: %s", err.Error())
 			return
 		}
 
+		definitions := []module.Definition{}
+
+		if From != "" {
+			if !util.FileExists(From) {
+				fmt.Printf("Service definition file %s is missing", From)
+				return
+			}
+
+			definition, err := module.LoadDefinition("", From)
+
+			if err != nil {
+				fmt.Printf("Error: %s", err.Error())
+				return
+			}
+
+			definitions = append(definitions, definition)
+		} else {
+			definitions, err = loadDefinitions(conf, project)
+
+			if err != nil {
+				fmt.Printf("Error: %s", err.Error())
+				return
+			}
+		}
+
 		data := []string{}
 		index := map[string]*model.Service{}
-		service := &model.Service{}
-
-		for _, v := range files {
-			if strings.Contains(v.Name, ".toml") {
+		endpoints := map[string]string{}
 
-				service = model.NewEmptyService(v.Name)
-				err = service.Decode(v.Path)
+		for _, definition := range definitions {
+			for _, end := range definition.Service.Endpoint {
+				endpointID := fmt.Sprintf("%s - %s", definition.Service.Main.ID, end.ID)
+				label := definition.Label(endpointID)
 
-				if err != nil {
-					fmt.Printf(
-						"Error while decoding service %s: %s",
-						v.Path,
-						err.Error(),
-					)
-					return
+				if From == "" {
+					label = serviceLabel(conf, definition, label)
 				}
 
-				for _, end := range service.Endpoint {
-					data = append(
-						data,
-						fmt.Sprintf("%s - %s", service.Main.ID, end.ID),
-					)
+				// Services with the same id in one directory are told apart by their file
+				if _, ok := index[label]; ok {
+					label = fmt.Sprintf("%s (%s)", definition.Label(endpointID), definition.Path)
+				}
 
-					index[fmt.Sprintf("%s - %s", service.Main.ID, end.ID)] = service
+				// Duplicate endpoint ids in one file are reported by the lint command
+				if _, ok := index[label]; ok {
+					continue
 				}
+
+				data = append(data, label)
+				index[label] = definition.Service
+				endpoints[label] = endpointID
 			}
 		}
 
 		result := ""
 		finder := module.FuzzyFinder{}
-		prompt := module.Prompt{}
 
 		if finder.Available() {
 			result, err = finder.Show(data)
@@ -123,61 +202,248 @@ var callCmd = &cobra.Command{
 			return
 		}
 
-		caller := module.NewCaller(module.NewHTTPClient())
-		fields := caller.GetFields(result, index[result])
+		service, ok := index[result]
 
-		val := ""
+		if !ok {
+			fmt.Printf("Error: Invalid endpoint %s", result)
+			return
+		}
 
-		for key, field := range fields {
-			if field.IsOptional {
-				val, err = prompt.Input(
-					field.Prompt,
-					module.Optional,
-				)
+		result = endpoints[result]
 
-				if module.IsEmpty(val) {
-					val = fields[key].Default
-				}
+		httpClient, err := newHTTPClient(conf)
 
-			} else {
-				val, err = prompt.Input(
-					field.Prompt,
-					module.NotEmpty,
-				)
+		if err != nil {
+			fmt.Printf("Error while configuring http transport: %s", err.Error())
+			return
+		}
+
+		caller := module.NewCaller(httpClient)
+		caller.Secrets = newSecretStore()
+		caller.Timing = Timing || Verbose
+		caller.Timeout, err = util.ParseTimeout(Timeout)
+
+		if err != nil {
+			fmt.Printf("Error: %s", err.Error())
+			return
+		}
+		caller.HTTPClient.OnRetry = func(attempt module.RetryAttempt) {
+			reason := fmt.Sprintf("status %d", attempt.StatusCode)
+
+			if attempt.Err != nil {
+				reason = attempt.Err.Error()
 			}
 
+			fmt.Printf(
+				"\r%s\n",
+				Yellow(fmt.Sprintf("Attempt %d failed with %s, retrying in %s", attempt.Number, reason, attempt.Wait.Round(time.Millisecond))),
+			)
+		}
+
+		err = caller.LoadBodyFile(result, service)
+
+		if err != nil {
+			fmt.Printf("Error while loading body file: %s", err.Error())
+			return
+		}
+
+		if Body != "" {
+			caller.SetBody(result, service, body)
+		}
+
+		endpoint, _ := caller.GetEndpoint(result, service)
+		download := Out != "" || endpoint.Download
+		caller.Download = download
+
+		if NoFollow || MaxRedirects >= 0 || KeepAuth {
+			caller.Redirect, err = caller.GetRedirectPolicy(service, endpoint)
+
 			if err != nil {
 				fmt.Printf("Error: %s", err.Error())
 				return
 			}
 
-			fields[key] = module.Field{
-				Prompt:     field.Prompt,
-				IsOptional: field.IsOptional,
-				Default:    field.Default,
-				Value:      val,
+			caller.Redirect.Follow = caller.Redirect.Follow && !NoFollow && MaxRedirects != 0
+			caller.Redirect.KeepAuth = caller.Redirect.KeepAuth || KeepAuth
+
+			if MaxRedirects > 0 {
+				caller.Redirect.MaxHops = MaxRedirects
+			}
+		}
+
+		if Resume && Out == "" {
+			fmt.Printf("Error: --resume requires --out")
+			return
+		}
+
+		offset := int64(0)
+
+		// Resume a partial download only if asked since the remote file may have changed
+		if Resume {
+			if fi, err := os.Stat(Out); err == nil && fi.Mode().IsRegular() && fi.Size() > 0 {
+				offset = fi.Size()
+				caller.AddHeader(result, service, "Range", fmt.Sprintf("bytes=%d-", offset))
 			}
 		}
 
+		err = module.CheckVariables(service)
+
+		if err != nil {
+			fmt.Printf("Error: %s", err.Error())
+			return
+		}
+
+		fields := caller.GetFields(result, service)
+
+		err = promptFields(&prompt, fields)
+
+		if err != nil {
+			fmt.Printf("Error: %s", err.Error())
+			return
+		}
+
 		spin := spinner.New(spinner.CharSets[26], 100*time.Millisecond)
 		spin.Color("green")
-		spin.Start()
 
-		response, err := caller.Call(result, index[result], fields)
+		if Dump || Verbose {
+			redact := append([]string{}, module.DefaultRedactHeaders...)
+			redact = append(redact, conf.General.RedactHeaders...)
+
+			if len(service.Security.APIKey.Header) > 0 {
+				redact = append(redact, service.Security.APIKey.Header[0])
+			}
+
+			fmt.Println()
+			caller.HTTPClient.Dumper = module.NewDumper(os.Stdout, redact, !download && !endpoint.Stream)
+			caller.HTTPClient.Dumper.Redactor = caller.HTTPClient.Redactor
+		} else {
+			spin.Start()
+		}
+
+		var jar *module.CookieJar
+
+		if service.Main.CookieJar {
+			jar, err = module.NewCookieJar(module.CookieJarPath(filepath.Dir(Config), service.Main.ID))
+
+			if err != nil {
+				spin.Stop()
+				fmt.Printf("Error while loading cookies: %s", err.Error())
+				return
+			}
+
+			caller.HTTPClient.Jar = jar
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		if strings.EqualFold(endpoint.Type, module.WebSocketEndpoint) {
+			spin.Stop()
+			callWebSocket(ctx, &caller, result, service, fields, jar)
+			return
+		}
+
+		if strings.EqualFold(service.Main.Type, module.GRPCService) {
+			callGRPC(ctx, &caller, result, service, fields, spin)
+			return
+		}
+
+		response, err := caller.CallWithContext(ctx, result, service, fields)
 
 		spin.Stop()
 
+		if err != nil && ctx.Err() != nil {
+			fmt.Println(Red("Request cancelled!"))
+			return
+		}
+
 		if err != nil {
-			fmt.Printf("Error: %s", err.Error())
+			fmt.Printf("Error: %s", caller.Redact(err.Error()))
 			return
 		}
 
+		if jar != nil {
+			err = jar.Save()
+
+			if err != nil {
+				fmt.Printf("Error while storing cookies: %s", err.Error())
+				return
+			}
+		}
+
 		if response == nil {
 			fmt.Println(Red("Invalid Response!"))
 			return
 		}
 
-		fmt.Println(caller.Pretty(response))
+		if !download && (endpoint.Stream || module.IsStream(response)) && response.StatusCode < http.StatusBadRequest {
+			fmt.Println(caller.PrettyHeaders(response))
+
+			count, err := caller.HTTPClient.Stream(response, Events, func(event module.StreamEvent) error {
+				fmt.Println(caller.Redact(event.Pretty()))
+				return nil
+			})
+
+			switch {
+			case err != nil && ctx.Err() != nil:
+				fmt.Println(Yellow(fmt.Sprintf("\nStream stopped after %d events", count)))
+			case err != nil && module.IsTimeout(err):
+				fmt.Println(Yellow(fmt.Sprintf("\nStream timed out after %d events", count)))
+			case err != nil:
+				fmt.Printf("Error while reading stream: %s", err.Error())
+				return
+			default:
+				fmt.Println(Green(fmt.Sprintf("\nStream ended after %d events", count)))
+			}
+
+			if redirects := caller.PrettyRedirects(response); redirects != "" {
+				fmt.Println(redirects)
+			}
+
+			if caller.Timing {
+				fmt.Println(caller.PrettyTiming(response))
+			}
+
+			return
+		}
+
+		if !download || (response.StatusCode >= http.StatusBadRequest &&
+			!(offset > 0 && response.StatusCode == http.StatusRequestedRangeNotSatisfiable)) {
+			fmt.Println(caller.Pretty(response))
+			return
+		}
+
+		fmt.Println(caller.PrettyHeaders(response))
+
+		path := Out
+
+		if path == "" {
+			path = caller.HTTPClient.Filename(response)
+
+			if util.PathExists(path) {
+				response.Body.Close()
+				fmt.Printf("Error: file %s already exists, use --out to override", path)
+				return
+			}
+		}
+
+		size, err := caller.HTTPClient.Download(response, path, offset, os.Stderr)
+
+		if err != nil {
+			fmt.Printf("Error while saving response to %s: %s", path, err.Error())
+			return
+		}
+
+		fmt.Println(Green(fmt.Sprintf("Saved %s to %s", util.FormatBytes(size), path)))
+		fmt.Println(caller.PrettyProtocol(response))
+
+		if redirects := caller.PrettyRedirects(response); redirects != "" {
+			fmt.Println(redirects)
+		}
+
+		if caller.Timing {
+			fmt.Println(caller.PrettyTiming(response))
+		}
 	},
 }
 
@@ -186,9 +452,238 @@ func init() {
 		&From,
 		"from",
 		"f",
-		"./.poodle.toml",
-		"service definition file",
+		"",
+		"service definition file, defaults to the .poodle.toml file and .poodle directory found up to the repository root and the services directory",
+	)
+	callCmd.PersistentFlags().StringVarP(
+		&Body,
+		"body",
+		"b",
+		"",
+		"request body to override the endpoint body, use @file.json to read from a file or - to read from stdin",
+	)
+	callCmd.PersistentFlags().StringVarP(
+		&Out,
+		"out",
+		"o",
+		"",
+		"save the response body to a file, an existing file is overwritten",
+	)
+	callCmd.PersistentFlags().BoolVar(
+		&Resume,
+		"resume",
+		false,
+		"resume a partial --out file with a range request if supported",
+	)
+	callCmd.PersistentFlags().BoolVarP(
+		&Timing,
+		"timing",
+		"t",
+		false,
+		"show request timing breakdown",
+	)
+	callCmd.PersistentFlags().BoolVarP(
+		&Dump,
+		"dump",
+		"d",
+		false,
+		"dump the request and response as sent on the wire",
+	)
+	callCmd.PersistentFlags().StringVar(
+		&Timeout,
+		"timeout",
+		"",
+		"request timeout like 500ms, 30s or 2m to override the endpoint timeout",
+	)
+	callCmd.PersistentFlags().BoolVar(
+		&NoFollow,
+		"no-follow",
+		false,
+		"don't follow redirects",
+	)
+	callCmd.PersistentFlags().IntVar(
+		&MaxRedirects,
+		"max-redirects",
+		-1,
+		"max redirects to follow, 0 to not follow",
+	)
+	callCmd.PersistentFlags().BoolVar(
+		&KeepAuth,
+		"keep-auth",
+		false,
+		"send auth headers to other hosts while following redirects",
+	)
+	callCmd.PersistentFlags().IntVar(
+		&Events,
+		"events",
+		0,
+		"stop streaming after receiving this number of events or grpc messages, 0 to stream until the end",
 	)
+	callCmd.PersistentFlags().StringArrayVar(
+		&Send,
+		"send",
+		[]string{},
+		"websocket message to send instead of the endpoint messages, use @file.json to read from a file",
+	)
+	callCmd.PersistentFlags().IntVar(
+		&Replies,
+		"replies",
+		-1,
+		"websocket replies to wait for, defaults to the endpoint replies or one per message",
+	)
+	callCmd.PersistentFlags().BoolVar(
+		&Batch,
+		"batch",
+		false,
+		"send the websocket messages and wait for the replies instead of an interactive session",
+	)
+}
+
+// callGRPC calls a grpc method and prints each response as it arrives
+func callGRPC(ctx context.Context, caller *module.Caller, endpointID string, service *model.Service, fields map[string]module.Field, spin *spinner.Spinner) {
+	first := true
+
+	result, err := caller.CallGRPC(ctx, endpointID, service, fields, Events, func(message string) error {
+		if first {
+			spin.Stop()
+			first = false
+		}
+
+		fmt.Println(caller.Redact(message))
+		return nil
+	})
+
+	spin.Stop()
+
+	if err != nil && ctx.Err() == context.Canceled {
+		fmt.Println(Red("Request cancelled!"))
+		return
+	}
+
+	if err != nil {
+		fmt.Printf("Error: %s", caller.Redact(err.Error()))
+		return
+	}
+
+	fmt.Println(caller.Redact(result.Pretty()))
+}
+
+// callWebSocket opens a websocket session with the endpoint
+func callWebSocket(ctx context.Context, caller *module.Caller, endpointID string, service *model.Service, fields map[string]module.Field, jar *module.CookieJar) {
+	conn, response, err := caller.CallWebSocket(ctx, endpointID, service, fields)
+
+	if err != nil && ctx.Err() != nil {
+		fmt.Println(Red("Request cancelled!"))
+		return
+	}
+
+	if jar != nil && response != nil {
+		if err := jar.Save(); err != nil {
+			if conn != nil {
+				conn.Close()
+			}
+
+			fmt.Printf("Error while storing cookies: %s", err.Error())
+			return
+		}
+	}
+
+	if err != nil {
+		if response != nil {
+			fmt.Println(caller.Pretty(response))
+		}
+
+		fmt.Printf("Error: %s", caller.Redact(err.Error()))
+		return
+	}
+
+	fmt.Println(caller.PrettyHeaders(response))
+
+	endpoint, _ := caller.GetEndpoint(endpointID, service)
+	messages, err := caller.WebSocketMessages(endpoint, fields)
+
+	if err != nil {
+		conn.Close()
+		fmt.Printf("Error: %s", caller.Redact(err.Error()))
+		return
+	}
+
+	session := module.NewWebSocketSession(conn, caller.HTTPClient.Redactor.Writer(os.Stdout), caller.HTTPClient.Timeout)
+
+	if !Batch && len(Send) == 0 && Replies < 0 {
+		fmt.Println(Yellow("Type a message and press enter to send it, :q or Ctrl-D to quit"))
+
+		for i, message := range messages {
+			fmt.Printf("%s %s\n", Cyan(fmt.Sprintf(":%d", i+1)), caller.Redact(message))
+		}
+
+		err = session.Interactive(ctx, os.Stdin, messages)
+
+		if err != nil && ctx.Err() == nil {
+			fmt.Printf("Error: %s", caller.Redact(err.Error()))
+		}
+
+		return
+	}
+
+	if len(Send) > 0 {
+		messages = []string{}
+
+		for _, item := range Send {
+			message, err := readBody(item)
+
+			if err != nil {
+				conn.Close()
+				fmt.Printf("Error while reading message %s: %s", item, err.Error())
+				return
+			}
+
+			messages = append(messages, message)
+		}
+	}
+
+	replies := Replies
+
+	if replies < 0 {
+		replies = endpoint.Replies
+	}
+
+	if replies <= 0 && Replies < 0 {
+		replies = len(messages)
+	}
+
+	count, err := session.Batch(ctx, messages, replies)
+
+	if err != nil && ctx.Err() != nil {
+		fmt.Println(Red("Session cancelled!"))
+		return
+	}
+
+	if err != nil {
+		fmt.Printf("Error: %s", caller.Redact(err.Error()))
+		return
+	}
+
+	fmt.Println(Green(fmt.Sprintf("Received %d replies", count)))
+}
+
+// readBody reads the body from a file if prefixed with @ or from stdin if -
+func readBody(value string) (string, error) {
+	if value == "-" {
+		data, err := ioutil.ReadAll(os.Stdin)
+
+		if err != nil {
+			return "", err
+		}
+
+		return string(data), nil
+	}
+
+	if strings.HasPrefix(value, "@") {
+		return util.ReadFile(strings.TrimPrefix(value, "@"))
+	}
+
+	return value, nil
 }
 
 func init() {
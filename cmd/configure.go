@@ -5,7 +5,6 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"os"
 
@@ -120,7 +119,7 @@ var configureCmd = &cobra.Command{
 		// Override github username
 		conf.Gist.Username = username
 
-		token, err := prompt.Input(
+		token, err := prompt.Secret(
 			fmt.Sprintf("Github OAuth Token:"),
 			module.NotEmpty,
 		)
@@ -130,9 +129,6 @@ var configureCmd = &cobra.Command{
 			return
 		}
 
-		// Override github token
-		conf.Gist.AccessToken = token
-
 		servicesDir, err := prompt.Input(
 			fmt.Sprintf("Services Definitions Directory:"),
 			module.NotEmpty,
@@ -154,14 +150,26 @@ var configureCmd = &cobra.Command{
 			return
 		}
 
+		ctx, cancel := newContext()
+		defer cancel()
+
+		httpClient, err := newHTTPClient(conf)
+
+		if err != nil {
+			fmt.Printf("Error while configuring http transport: %s", err.Error())
+			return
+		}
+
+		httpClient.Retry = module.NewRetryPolicy()
+
 		githubClient := module.NewGithubClient(
-			module.NewHTTPClient(),
+			httpClient,
 			module.GithubAPI,
 			conf.Gist.Username,
-			conf.Gist.AccessToken,
+			token,
 		)
 
-		oauth, err := githubClient.Check(context.TODO())
+		oauth, err := githubClient.Check(ctx)
 
 		if err != nil {
 			fmt.Printf(
@@ -180,6 +188,16 @@ var configureCmd = &cobra.Command{
 			return
 		}
 
+		// Keep the token in the secret store and only its reference in the configs
+		err = newSecretStore().Set(module.GithubSecretKey, token)
+
+		if err != nil {
+			fmt.Printf("Error while storing github token: %s", err.Error())
+			return
+		}
+
+		conf.Gist.AccessToken = module.SecretRef(module.GithubSecretKey)
+
 		err = conf.Encode(Config)
 
 		if err != nil {
@@ -0,0 +1,98 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/clivern/poodle/core/module"
+
+	. "github.com/logrusorgru/aurora/v3"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var cookiesCmd = &cobra.Command{
+	Use:   "cookies",
+	Short: "Manage services stored cookies",
+}
+
+var cookiesListCmd = &cobra.Command{
+	Use:   "list <service>",
+	Short: "List the stored cookies of a service",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if Verbose {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		log.Debug("Cookies list command got called.")
+
+		jar, err := module.NewCookieJar(module.CookieJarPath(filepath.Dir(Config), args[0]))
+
+		if err != nil {
+			fmt.Printf("Error while loading cookies: %s", err.Error())
+			return
+		}
+
+		items := jar.List()
+
+		if len(items) == 0 {
+			fmt.Println(Yellow(fmt.Sprintf("No cookies stored for service %s", args[0])))
+			return
+		}
+
+		for _, item := range items {
+			expires := "session"
+
+			if !item.Cookie.Expires.IsZero() {
+				expires = item.Cookie.Expires.Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Printf(
+				"%s=%s %s %s %s\n",
+				Cyan(item.Cookie.Name),
+				item.Cookie.Value,
+				Blue(fmt.Sprintf("url=%s", item.URL)),
+				Blue(fmt.Sprintf("path=%s", item.Cookie.Path)),
+				Yellow(fmt.Sprintf("expires=%s", expires)),
+			)
+		}
+	},
+}
+
+var cookiesClearCmd = &cobra.Command{
+	Use:   "clear <service>",
+	Short: "Clear the stored cookies of a service",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if Verbose {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		log.Debug("Cookies clear command got called.")
+
+		// Skip loading so that corrupted files can be cleared too
+		jar := &module.CookieJar{
+			Path: module.CookieJarPath(filepath.Dir(Config), args[0]),
+		}
+
+		err := jar.Clear()
+
+		if err != nil {
+			fmt.Printf("Error while clearing cookies: %s", err.Error())
+			return
+		}
+
+		fmt.Println(Green(fmt.Sprintf("Cookies of service %s cleared", args[0])))
+	},
+}
+
+func init() {
+	cookiesCmd.AddCommand(cookiesListCmd)
+	cookiesCmd.AddCommand(cookiesClearCmd)
+	rootCmd.AddCommand(cookiesCmd)
+}
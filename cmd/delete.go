@@ -5,7 +5,6 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -53,43 +52,20 @@ var deleteCmd = &cobra.Command{
 			return
 		}
 
-		files, err := util.ListFiles(util.EnsureTrailingSlash(conf.Services.Directory))
+		definitions, err := loadDefinitions(conf, nil)
 
 		if err != nil {
-			fmt.Printf(
-				"Error while listing services under %s: %s",
-				util.EnsureTrailingSlash(conf.Services.Directory),
-				err.Error(),
-			)
+			fmt.Printf("Error: %s", err.Error())
 			return
 		}
 
 		data := []string{}
-		index := map[string]string{}
-		service := &model.Service{}
-
-		for _, v := range files {
-			if strings.Contains(v.Name, ".toml") {
-
-				service = model.NewService(v.Name)
-				err = service.Decode(v.Path)
-
-				if err != nil {
-					fmt.Printf(
-						"Error while decoding service %s: %s",
-						v.Path,
-						err.Error(),
-					)
-					return
-				}
-
-				data = append(
-					data,
-					service.Main.ID,
-				)
+		index := map[string]module.Definition{}
 
-				index[service.Main.ID] = v.Path
-			}
+		for _, definition := range definitions {
+			name := serviceLabel(conf, definition, definition.Label(definition.Service.Main.ID))
+			data = append(data, name)
+			index[name] = definition
 		}
 
 		result := ""
@@ -110,6 +86,13 @@ var deleteCmd = &cobra.Command{
 			return
 		}
 
+		definition, found := index[result]
+
+		if !found {
+			fmt.Printf("Error: Invalid service %s", result)
+			return
+		}
+
 		choice, err := prompt.Select(
 			fmt.Sprintf("Are you sure"),
 			[]string{"No", "Yes"},
@@ -129,27 +112,49 @@ var deleteCmd = &cobra.Command{
 		spin.Color("green")
 		spin.Start()
 
-		err = util.DeleteFile(index[result])
+		err = util.DeleteFile(definition.Path)
 
 		if err != nil {
 			fmt.Printf("Error: %s", err.Error())
 		}
 
-		// Delete remotely
-		if conf.Gist.Username == "" || conf.Gist.AccessToken == "" {
+		// Delete remotely, only the services directory is synced
+		if conf.Gist.Username == "" || conf.Gist.AccessToken == "" ||
+			definition.Root != util.RemoveTrailingSlash(conf.Services.Directory) {
 			spin.Stop()
 			fmt.Println(Green("Service file deleted successfully!"))
 			return
 		}
 
+		ctx, cancel := newContext()
+		defer cancel()
+
+		httpClient, err := newHTTPClient(conf)
+
+		if err != nil {
+			spin.Stop()
+			fmt.Printf("Error while configuring http transport: %s", err.Error())
+			return
+		}
+
+		httpClient.Retry = module.NewRetryPolicy()
+
+		token, err := githubToken(conf)
+
+		if err != nil {
+			spin.Stop()
+			fmt.Printf("Error while loading github token: %s", err.Error())
+			return
+		}
+
 		githubClient := module.NewGithubClient(
-			module.NewHTTPClient(),
+			httpClient,
 			module.GithubAPI,
 			conf.Gist.Username,
-			conf.Gist.AccessToken,
+			token,
 		)
 
-		oauth, err := githubClient.Check(context.TODO())
+		oauth, err := githubClient.Check(ctx)
 
 		if err != nil {
 			spin.Stop()
@@ -163,7 +168,7 @@ var deleteCmd = &cobra.Command{
 			return
 		}
 
-		remoteGist, err := githubClient.GetGist(context.TODO(), conf.Gist.GistID)
+		remoteGist, err := githubClient.GetGist(ctx, conf.Gist.GistID)
 
 		if err != nil {
 			fmt.Printf(
@@ -188,8 +193,8 @@ var deleteCmd = &cobra.Command{
 			}
 
 			delete(remoteFs.Files, strings.Replace(
-				index[result],
-				util.EnsureTrailingSlash(conf.Services.Directory),
+				definition.Path,
+				util.EnsureTrailingSlash(definition.Root),
 				"",
 				-1,
 			))
@@ -219,7 +224,7 @@ var deleteCmd = &cobra.Command{
 		}
 
 		_, err = githubClient.UpdateGist(
-			context.TODO(),
+			ctx,
 			conf.Gist.GistID,
 			module.Gist{
 				Description: "Poodle",
@@ -6,7 +6,6 @@ package cmd
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/clivern/poodle/core/model"
 	"github.com/clivern/poodle/core/module"
@@ -52,33 +51,28 @@ var editCmd = &cobra.Command{
 		prompt := module.Prompt{}
 		finder := module.FuzzyFinder{}
 
-		localFiles, err := util.ListFiles(util.EnsureTrailingSlash(conf.Services.Directory))
+		definitions, err := loadDefinitions(conf, nil)
 
 		if err != nil {
-			fmt.Printf(
-				"Error while listing local files in %s: %s",
-				util.EnsureTrailingSlash(conf.Services.Directory),
-				err.Error(),
-			)
+			fmt.Printf("Error: %s", err.Error())
 			return
 		}
 
 		files := []string{}
+		index := map[string]string{}
 
-		for _, file := range localFiles {
-			if !strings.Contains(file.Name, ".toml") {
-				continue
-			}
-
-			files = append(files, strings.Replace(file.Name, ".toml", "", -1))
+		for _, definition := range definitions {
+			name := serviceLabel(conf, definition, definition.Name())
+			files = append(files, name)
+			index[name] = definition.Path
 		}
 
-		relPath := ""
+		result := ""
 
 		if finder.Available() {
-			relPath, err = finder.Show(files)
+			result, err = finder.Show(files)
 		} else {
-			relPath, err = prompt.Select(
+			result, err = prompt.Select(
 				fmt.Sprintf("Select a Service"),
 				files,
 			)
@@ -89,11 +83,12 @@ var editCmd = &cobra.Command{
 			return
 		}
 
-		absPath := fmt.Sprintf(
-			"%s%s.toml",
-			util.EnsureTrailingSlash(conf.Services.Directory),
-			relPath,
-		)
+		absPath, ok := index[result]
+
+		if !ok {
+			fmt.Printf("Error: Invalid service %s", result)
+			return
+		}
 
 		editor := module.Editor{}
 		err = editor.Edit(absPath)
@@ -0,0 +1,197 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/core/module"
+	"github.com/clivern/poodle/core/util"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// GraphQLURI var
+var GraphQLURI string
+
+// introspectionEndpoint is the ID of the endpoint sending the introspection query
+const introspectionEndpoint = "__introspection"
+
+var graphqlCmd = &cobra.Command{
+	Use:   "graphql",
+	Short: "Work with graphql services",
+}
+
+var graphqlIntrospectCmd = &cobra.Command{
+	Use:   "introspect <service>",
+	Short: "Generate endpoint stubs for each query and mutation of a graphql service",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if Verbose {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		log.Debug("Graphql introspect command got called.")
+
+		if !util.FileExists(Config) {
+			fmt.Printf(
+				"Config file is missing %s, Please start with $ poodle configure",
+				Config,
+			)
+			return
+		}
+
+		conf := model.NewConfigs()
+		err := conf.Decode(Config)
+
+		if err != nil {
+			fmt.Printf(
+				"Error while decoding configs %s: %s",
+				Config,
+				err.Error(),
+			)
+			return
+		}
+
+		project, err := loadProject(conf)
+
+		if err != nil {
+			fmt.Printf("Error: %s", err.Error())
+			return
+		}
+
+		service, err := findService(conf, project, args[0])
+
+		if err != nil {
+			fmt.Printf("Error: %s", err.Error())
+			return
+		}
+
+		uri := GraphQLURI
+
+		// Send the query to the first graphql endpoint of the service by default
+		for _, end := range service.Endpoint {
+			if uri == "" && strings.EqualFold(end.Type, module.GraphQLEndpoint) {
+				uri = end.URI
+			}
+		}
+
+		if uri == "" {
+			uri = module.DefaultGraphQLURI
+		}
+
+		service.Endpoint = append(service.Endpoint, model.Endpoint{
+			ID:     introspectionEndpoint,
+			Type:   module.GraphQLEndpoint,
+			Method: http.MethodPost,
+			URI:    uri,
+			Query:  module.IntrospectionQuery,
+		})
+
+		httpClient, err := newHTTPClient(conf)
+
+		if err != nil {
+			fmt.Printf("Error while configuring http transport: %s", err.Error())
+			return
+		}
+
+		caller := module.NewCaller(httpClient)
+		caller.Secrets = newSecretStore()
+		endpointID := fmt.Sprintf("%s - %s", service.Main.ID, introspectionEndpoint)
+
+		err = module.CheckVariables(service)
+
+		if err != nil {
+			fmt.Printf("Error: %s", err.Error())
+			return
+		}
+
+		fields := caller.GetFields(endpointID, service)
+		prompt := module.Prompt{}
+
+		err = promptFields(&prompt, fields)
+
+		if err != nil {
+			fmt.Printf("Error: %s", err.Error())
+			return
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		response, err := caller.CallWithContext(ctx, endpointID, service, fields)
+
+		if err != nil {
+			fmt.Printf("Error while introspecting service %s: %s", service.Main.ID, caller.Redact(err.Error()))
+			return
+		}
+
+		defer response.Body.Close()
+
+		body, err := ioutil.ReadAll(response.Body)
+
+		if err != nil {
+			fmt.Printf("Error while reading introspection response: %s", err.Error())
+			return
+		}
+
+		if response.StatusCode != http.StatusOK {
+			fmt.Printf("Error while introspecting service %s: Invalid status code %d", service.Main.ID, response.StatusCode)
+			return
+		}
+
+		schema, err := module.ParseGraphQLSchema(body)
+
+		if err != nil {
+			fmt.Printf("Error: %s", err.Error())
+			return
+		}
+
+		stubs, err := module.RenderEndpoints(schema.Endpoints(uri))
+
+		if err != nil {
+			fmt.Printf("Error while rendering endpoints: %s", err.Error())
+			return
+		}
+
+		fmt.Print(stubs)
+	},
+}
+
+// findService finds a service definition by its ID
+func findService(conf *model.Configs, project *module.Project, id string) (*model.Service, error) {
+	definitions, err := loadDefinitions(conf, project)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Project definitions come first so they take precedence
+	for _, definition := range definitions {
+		if definition.Service.Main.ID == id {
+			return definition.Service, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Unable to find service %s", id)
+}
+
+func init() {
+	graphqlIntrospectCmd.Flags().StringVarP(
+		&GraphQLURI,
+		"uri",
+		"u",
+		"",
+		"graphql endpoint uri, defaults to the first graphql endpoint uri or /graphql",
+	)
+
+	graphqlCmd.AddCommand(graphqlIntrospectCmd)
+	rootCmd.AddCommand(graphqlCmd)
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/core/module"
+	"github.com/clivern/poodle/core/util"
+
+	. "github.com/logrusorgru/aurora/v3"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [file...]",
+	Short: "Validate service definition files",
+	Long: `Validate service definition files, the project and services directories
+definitions are checked if no files are provided`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if Verbose {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		log.Debug("Lint command got called.")
+
+		files := args
+
+		if len(files) == 0 {
+			var err error
+
+			files, err = definitionFiles()
+
+			if err != nil {
+				fmt.Printf("Error: %s", err.Error())
+				os.Exit(1)
+			}
+		}
+
+		linter := module.NewLinter()
+
+		for _, file := range files {
+			log.WithFields(log.Fields{
+				"file": file,
+			}).Debug("Lint service file")
+
+			linter.Lint(file)
+		}
+
+		for _, item := range linter.Diagnostics {
+			if item.Severity == module.ErrorSeverity {
+				fmt.Println(Red(item.String()))
+			} else {
+				fmt.Println(Yellow(item.String()))
+			}
+		}
+
+		errors := linter.Errors()
+		warnings := len(linter.Diagnostics) - errors
+
+		if errors > 0 {
+			fmt.Println(Red(fmt.Sprintf("%d files checked, %d errors and %d warnings found", len(files), errors, warnings)))
+			os.Exit(1)
+		}
+
+		fmt.Println(Green(fmt.Sprintf("%d files checked, %d warnings found", len(files), warnings)))
+	},
+}
+
+// definitionFiles lists the project definition files then the services directories ones
+func definitionFiles() ([]string, error) {
+	conf := model.NewConfigs()
+
+	if util.FileExists(Config) {
+		if err := conf.Decode(Config); err != nil {
+			return nil, fmt.Errorf("Unable to decode configs %s: %s", Config, err.Error())
+		}
+	}
+
+	project, err := loadProject(conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+
+	if project != nil {
+		files, err = project.Files()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range conf.Services.Paths() {
+		if !util.DirExists(path) {
+			continue
+		}
+
+		items, err := module.DefinitionFiles(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, items...)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No service definition files found")
+	}
+
+	return files, nil
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+}
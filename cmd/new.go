@@ -6,6 +6,8 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -59,10 +61,11 @@ var newCmd = &cobra.Command{
 					return fmt.Errorf("Input must not be empty")
 				}
 
-				match, err := regexp.MatchString("^[A-Za-z0-9-_/]+$", input)
+				// A / in the id stores the service in a subfolder like team/payments
+				match, err := regexp.MatchString("^[A-Za-z0-9-_]+(/[A-Za-z0-9-_]+)*$", input)
 
 				if !match || err != nil {
-					return fmt.Errorf("Service Id must be alphanumeric")
+					return fmt.Errorf("Service Id must be alphanumeric, folders are separated by /")
 				}
 
 				path := fmt.Sprintf(
@@ -90,7 +93,15 @@ var newCmd = &cobra.Command{
 			relPath,
 		)
 
-		service := model.NewService(relPath)
+		err = os.MkdirAll(filepath.Dir(absPath), 0775)
+
+		if err != nil {
+			fmt.Printf("Error: %s", err.Error())
+			return
+		}
+
+		// The folder is shown as the service namespace
+		service := model.NewService(filepath.Base(relPath))
 		err = service.Encode(absPath)
 
 		if err != nil {
@@ -5,9 +5,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/core/module"
 	"github.com/clivern/poodle/core/util"
 
 	"github.com/spf13/cobra"
@@ -41,6 +47,155 @@ func init() {
 	)
 }
 
+// newContext creates a context that gets cancelled on Ctrl-C or termination
+func newContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// newHTTPClient configures the shared transport and creates a http client on top of it
+func newHTTPClient(conf *model.Configs) (*module.HTTPClient, error) {
+	config, err := module.NewTransportConfigFromConfigs(conf.HTTP)
+
+	if err != nil {
+		return nil, err
+	}
+
+	module.ConfigureTransport(config)
+
+	httpClient := module.NewHTTPClient()
+	httpClient.Use(module.LoggingMiddleware(httpClient.Redactor))
+
+	return httpClient, nil
+}
+
+// newSecretStore creates the secret store, the OS keyring with an encrypted file beside the config as fallback
+func newSecretStore() module.SecretStore {
+	return module.NewKeyring(module.NewFileStore(filepath.Dir(Config)))
+}
+
+// promptFields asks for the fields values, enums are selected from a list and secrets are typed without echo
+func promptFields(prompt *module.Prompt, fields map[string]module.Field) error {
+	for key, field := range fields {
+		var val string
+		var err error
+
+		if len(field.Variable.Enum) > 0 {
+			val, err = prompt.Select(
+				field.Prompt,
+				field.Choices(),
+			)
+		} else if field.IsSecret() {
+			val, err = prompt.Secret(
+				field.Prompt,
+				field.Validator(),
+			)
+		} else {
+			val, err = prompt.Input(
+				field.Prompt,
+				field.Validator(),
+			)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if field.IsOptional && module.IsEmpty(val) {
+			val = field.Default
+		}
+
+		field.Value = val
+		fields[key] = field
+	}
+
+	return nil
+}
+
+// githubToken gets the gist access token resolving it if it is a secret reference
+func githubToken(conf *model.Configs) (string, error) {
+	return module.ReplaceSecrets(conf.Gist.AccessToken, newSecretStore(), nil)
+}
+
+// loadProject finds the project of the working directory and applies its config over the configs
+func loadProject(conf *model.Configs) (*module.Project, error) {
+	wd, err := os.Getwd()
+
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := module.FindProject(wd)
+
+	if err != nil || project == nil {
+		return nil, err
+	}
+
+	for _, path := range project.ConfigFiles() {
+		if err := conf.Override(path); err != nil {
+			return nil, fmt.Errorf("Unable to decode project config %s: %s", path, err.Error())
+		}
+	}
+
+	return project, nil
+}
+
+// loadDefinitions loads the project service definitions then the ones of the services directories
+//
+// Definitions are labeled by source only if the project has definitions, missing
+// directories are skipped unless no definitions are found.
+func loadDefinitions(conf *model.Configs, project *module.Project) ([]module.Definition, error) {
+	definitions := []module.Definition{}
+
+	if project != nil {
+		items, err := project.Definitions()
+
+		if err != nil {
+			return nil, err
+		}
+
+		definitions = append(definitions, items...)
+	}
+
+	source := ""
+
+	if len(definitions) > 0 {
+		source = module.GlobalSource
+	}
+
+	for _, path := range conf.Services.Paths() {
+		if !util.DirExists(path) {
+			continue
+		}
+
+		items, err := module.LoadDefinitions(source, path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		definitions = append(definitions, items...)
+	}
+
+	if len(definitions) == 0 && !util.DirExists(conf.Services.Directory) {
+		return nil, fmt.Errorf(
+			"Unable to list services under %s: directory is missing",
+			util.EnsureTrailingSlash(conf.Services.Directory),
+		)
+	}
+
+	return definitions, nil
+}
+
+// serviceLabel gets the label of a service definition in the finder, the directory
+// is shown if services are stored in more than one
+func serviceLabel(conf *model.Configs, definition module.Definition, name string) string {
+	if len(conf.Services.Paths()) < 2 {
+		return name
+	}
+
+	return fmt.Sprintf("%s (%s)", name, definition.Root)
+}
+
 // Execute runs cmd tool
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
@@ -0,0 +1,105 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/clivern/poodle/core/module"
+
+	. "github.com/logrusorgru/aurora/v3"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage secrets referenced as {$secret:keyring:<key>}",
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set <key>",
+	Short: "Store a secret in the OS keyring or the encrypted file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if Verbose {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		log.Debug("Secret set command got called.")
+
+		prompt := module.Prompt{}
+
+		value, err := prompt.Secret(
+			fmt.Sprintf("Secret %s:", args[0]),
+			module.NotEmpty,
+		)
+
+		if err != nil {
+			fmt.Printf("Error: %s", err.Error())
+			return
+		}
+
+		err = newSecretStore().Set(args[0], value)
+
+		if err != nil {
+			fmt.Printf("Error while storing secret %s: %s", args[0], err.Error())
+			return
+		}
+
+		fmt.Println(Green(fmt.Sprintf("Secret %s stored, use it as %s", args[0], module.SecretRef(args[0]))))
+	},
+}
+
+var secretGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a stored secret",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if Verbose {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		log.Debug("Secret get command got called.")
+
+		value, err := newSecretStore().Get(args[0])
+
+		if err != nil {
+			fmt.Printf("Error while loading secret %s: %s", args[0], err.Error())
+			return
+		}
+
+		fmt.Println(value)
+	},
+}
+
+var secretRmCmd = &cobra.Command{
+	Use:   "rm <key>",
+	Short: "Delete a stored secret",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if Verbose {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		log.Debug("Secret rm command got called.")
+
+		err := newSecretStore().Delete(args[0])
+
+		if err != nil {
+			fmt.Printf("Error while deleting secret %s: %s", args[0], err.Error())
+			return
+		}
+
+		fmt.Println(Green(fmt.Sprintf("Secret %s deleted", args[0])))
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretSetCmd)
+	secretCmd.AddCommand(secretGetCmd)
+	secretCmd.AddCommand(secretRmCmd)
+	rootCmd.AddCommand(secretCmd)
+}
@@ -5,7 +5,6 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -57,14 +56,33 @@ var syncCmd = &cobra.Command{
 			return
 		}
 
+		ctx, cancel := newContext()
+		defer cancel()
+
+		httpClient, err := newHTTPClient(conf)
+
+		if err != nil {
+			fmt.Printf("Error while configuring http transport: %s", err.Error())
+			return
+		}
+
+		httpClient.Retry = module.NewRetryPolicy()
+
+		token, err := githubToken(conf)
+
+		if err != nil {
+			fmt.Printf("Error while loading github token: %s", err.Error())
+			return
+		}
+
 		githubClient := module.NewGithubClient(
-			module.NewHTTPClient(),
+			httpClient,
 			module.GithubAPI,
 			conf.Gist.Username,
-			conf.Gist.AccessToken,
+			token,
 		)
 
-		oauth, err := githubClient.Check(context.TODO())
+		oauth, err := githubClient.Check(ctx)
 
 		if err != nil {
 			fmt.Printf(
@@ -87,7 +105,7 @@ var syncCmd = &cobra.Command{
 
 		// Validate if gist still exist
 		if strings.TrimSpace(conf.Gist.GistID) != "" {
-			res, err := githubClient.GetGist(context.TODO(), conf.Gist.GistID)
+			res, err := githubClient.GetGist(ctx, conf.Gist.GistID)
 
 			if err != nil || res.ID == "" {
 				found = false
@@ -127,7 +145,7 @@ var syncCmd = &cobra.Command{
 				Filename: "poodle",
 			}
 
-			result, err := githubClient.CreateGist(context.TODO(), module.Gist{
+			result, err := githubClient.CreateGist(ctx, module.Gist{
 				Description: "Poodle",
 				Public:      conf.Gist.Public,
 				Files:       files,
@@ -155,7 +173,7 @@ var syncCmd = &cobra.Command{
 			}
 		}
 
-		remoteGist, err := githubClient.GetGist(context.TODO(), conf.Gist.GistID)
+		remoteGist, err := githubClient.GetGist(ctx, conf.Gist.GistID)
 
 		if err != nil {
 			fmt.Printf(
@@ -219,7 +237,7 @@ var syncCmd = &cobra.Command{
 		}
 
 		_, err = githubClient.UpdateGist(
-			context.TODO(),
+			ctx,
 			conf.Gist.GistID,
 			module.Gist{
 				Description: "Poodle",
@@ -241,6 +259,11 @@ var syncCmd = &cobra.Command{
 		spin.Stop()
 
 		fmt.Println(Green("Already up-to-date"))
+
+		// Only the primary directory is synced
+		for _, path := range conf.Services.Extra() {
+			fmt.Println(Yellow(fmt.Sprintf("Skipped %s, only the services directory %s is synced", path, conf.Services.Directory)))
+		}
 	},
 }
 
@@ -7,6 +7,7 @@ package model
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/clivern/poodle/core/util"
 
@@ -18,15 +19,17 @@ type Configs struct {
 	General  General  `toml:"General"`
 	Gist     Gist     `toml:"Gist"`
 	Services Services `toml:"Services"`
+	HTTP     HTTP     `toml:"HTTP"`
 }
 
 // General type
 type General struct {
-	Editor    string `toml:"editor"`
-	Column    int    `toml:"column"`
-	Selectcmd string `toml:"selectcmd"`
-	Backend   string `toml:"backend"`
-	Sortby    string `toml:"sortby"`
+	Editor        string   `toml:"editor"`
+	Column        int      `toml:"column"`
+	Selectcmd     string   `toml:"selectcmd"`
+	Backend       string   `toml:"backend"`
+	Sortby        string   `toml:"sortby"`
+	RedactHeaders []string `toml:"redact_headers"`
 }
 
 // Gist type
@@ -38,9 +41,22 @@ type Gist struct {
 	AutoSync    bool   `toml:"auto_sync"`
 }
 
+// HTTP type
+type HTTP struct {
+	MaxIdleConns        int    `toml:"max_idle_conns"`
+	MaxIdleConnsPerHost int    `toml:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int    `toml:"max_conns_per_host"`
+	IdleConnTimeout     string `toml:"idle_conn_timeout"`
+	DisableHTTP2        bool   `toml:"disable_http2"`
+	DisableCompression  bool   `toml:"disable_compression"`
+}
+
 // Services type
 type Services struct {
+	// Directory is the primary services directory, it is the only one synced
 	Directory string `toml:"directory"`
+	// Directories are extra directories to list services from
+	Directories []string `toml:"directories"`
 }
 
 // NewConfigs creates an instance of Configs
@@ -64,6 +80,32 @@ func NewConfigs() *Configs {
 	}
 }
 
+// Paths gets the services directory then the extra directories without duplicates
+func (s Services) Paths() []string {
+	paths := []string{}
+
+	for _, path := range append([]string{s.Directory}, s.Directories...) {
+		path = util.RemoveTrailingSlash(path)
+
+		if path != "" && !util.InArray(path, paths) {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+// Extra gets the extra directories that are not the primary one
+func (s Services) Extra() []string {
+	paths := s.Paths()
+
+	if len(paths) > 0 && paths[0] == util.RemoveTrailingSlash(s.Directory) {
+		return paths[1:]
+	}
+
+	return paths
+}
+
 // Decode decodes from file to struct
 func (g *Configs) Decode(path string) error {
 	if _, err := toml.DecodeFile(path, &g); err != nil {
@@ -73,6 +115,37 @@ func (g *Configs) Decode(path string) error {
 	return nil
 }
 
+// Override decodes a project config over the configs
+//
+// The gist settings are kept since they hold credentials and relative services
+// directories are resolved from the config file directory.
+func (g *Configs) Override(path string) error {
+	gist := g.Gist
+	services := g.Services
+	g.Services = Services{}
+
+	if _, err := toml.DecodeFile(path, g); err != nil {
+		return err
+	}
+
+	g.Gist = gist
+	g.Services.Directory = relativeTo(path, g.Services.Directory)
+
+	for i, directory := range g.Services.Directories {
+		g.Services.Directories[i] = relativeTo(path, directory)
+	}
+
+	if g.Services.Directory == "" {
+		g.Services.Directory = services.Directory
+	}
+
+	if g.Services.Directories == nil {
+		g.Services.Directories = services.Directories
+	}
+
+	return nil
+}
+
 // Encode encodes struct and store on file
 func (g *Configs) Encode(path string) error {
 	f, err := os.Create(path)
@@ -91,3 +164,12 @@ func (g *Configs) Encode(path string) error {
 
 	return nil
 }
+
+// relativeTo resolves a relative path from the directory of a file
+func relativeTo(file, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(filepath.Dir(file), path)
+}
@@ -35,34 +35,94 @@ type Security struct {
 	Bearer Bearer `toml:"Bearer"`
 }
 
+// Retry type
+type Retry struct {
+	MaxAttempts   int    `toml:"max_attempts"`
+	Backoff       string `toml:"backoff"`
+	MaxBackoff    string `toml:"max_backoff"`
+	Statuses      []int  `toml:"statuses"`
+	NetworkErrors bool   `toml:"network_errors"`
+	NonIdempotent bool   `toml:"non_idempotent"`
+}
+
+// Redirect type
+type Redirect struct {
+	Policy   string `toml:"policy"`
+	MaxHops  int    `toml:"max_hops"`
+	KeepAuth bool   `toml:"keep_auth"`
+}
+
+// Variable type
+type Variable struct {
+	Type        string   `toml:"type"`
+	Pattern     string   `toml:"pattern"`
+	Enum        []string `toml:"enum"`
+	Description string   `toml:"description"`
+	Secret      bool     `toml:"secret"`
+}
+
 // Main type
 type Main struct {
-	ID          string     `toml:"id"`
-	Name        string     `toml:"name"`
-	Description string     `toml:"description"`
-	Timeout     string     `toml:"timeout"`
-	ServiceURL  string     `toml:"service_url"`
-	Headers     [][]string `toml:"headers"`
+	ID             string     `toml:"id"`
+	Name           string     `toml:"name"`
+	Description    string     `toml:"description"`
+	Timeout        string     `toml:"timeout"`
+	ConnectTimeout string     `toml:"connect_timeout"`
+	ReadTimeout    string     `toml:"read_timeout"`
+	ServiceURL     string     `toml:"service_url"`
+	Protocol       string     `toml:"protocol"`
+	Type           string     `toml:"type"`
+	Descriptors    []string   `toml:"descriptors"`
+	ImportPaths    []string   `toml:"import_paths"`
+	Headers        [][]string `toml:"headers"`
+	CookieJar      bool       `toml:"cookie_jar"`
+	Retry          Retry      `toml:"Retry"`
+}
+
+// FormField type
+type FormField struct {
+	Name  string `toml:"name"`
+	Value string `toml:"value"`
+	File  string `toml:"file"`
 }
 
 // Endpoint type
 type Endpoint struct {
-	ID          string     `toml:"id"`
-	Name        string     `toml:"name"`
-	Description string     `toml:"description"`
-	Method      string     `toml:"method"`
-	Headers     [][]string `toml:"headers"`
-	Parameters  [][]string `toml:"parameters"`
-	URI         string     `toml:"uri"`
-	Body        string     `toml:"body"`
-	Public      bool       `toml:"public"`
+	ID             string                 `toml:"id"`
+	Name           string                 `toml:"name"`
+	Description    string                 `toml:"description"`
+	Type           string                 `toml:"type"`
+	Method         string                 `toml:"method"`
+	Headers        [][]string             `toml:"headers"`
+	Parameters     [][]string             `toml:"parameters"`
+	URI            string                 `toml:"uri"`
+	Body           string                 `toml:"body"`
+	BodyFile       string                 `toml:"body_file"`
+	BodyType       string                 `toml:"body_type"`
+	Form           []FormField            `toml:"Form"`
+	Query          string                 `toml:"query"`
+	QueryFile      string                 `toml:"query_file"`
+	OperationName  string                 `toml:"operation_name"`
+	Variables      map[string]interface{} `toml:"variables"`
+	Messages       []string               `toml:"messages"`
+	Replies        int                    `toml:"replies"`
+	Public         bool                   `toml:"public"`
+	Download       bool                   `toml:"download"`
+	Stream         bool                   `toml:"stream"`
+	Timeout        string                 `toml:"timeout"`
+	ConnectTimeout string                 `toml:"connect_timeout"`
+	ReadTimeout    string                 `toml:"read_timeout"`
+	Retry          Retry                  `toml:"Retry"`
+	Redirect       Redirect               `toml:"Redirect"`
 }
 
 // Service type
 type Service struct {
-	Main     Main       `toml:"Main"`
-	Security Security   `toml:"Security"`
-	Endpoint []Endpoint `toml:"Endpoint"`
+	Main      Main                `toml:"Main"`
+	Security  Security            `toml:"Security"`
+	Variables map[string]Variable `toml:"Variables"`
+	Endpoint  []Endpoint          `toml:"Endpoint"`
+	Path      string              `toml:"-"`
 }
 
 // NewService creates an instance of Service
@@ -214,6 +274,8 @@ func (s *Service) Decode(path string) error {
 		return err
 	}
 
+	s.Path = path
+
 	return nil
 }
 
@@ -8,9 +8,9 @@ import (
 	"context"
 	b64 "encoding/base64"
 	"fmt"
+	"io"
 	"net/http"
-	"regexp"
-	"strconv"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -22,6 +22,20 @@ import (
 // Caller struct
 type Caller struct {
 	HTTPClient *HTTPClient
+	// Timing shows the request timing breakdown in the pretty output
+	Timing bool
+	// Redirect overrides the endpoint redirect policy if set
+	Redirect *RedirectPolicy
+	// Timeout overrides the endpoint and service timeout if set
+	Timeout time.Duration
+	// Download saves the response to a file, only the connect and read timeouts apply to it
+	Download bool
+	// Secrets resolves the keyring secret references like {$secret:keyring:poodle/github}
+	Secrets SecretStore
+
+	secrets map[string]string
+	// service is the prepared service, paths in functions are relative to its file
+	service *model.Service
 }
 
 // Field struct
@@ -30,6 +44,8 @@ type Field struct {
 	IsOptional bool
 	Default    string
 	Value      string
+	// Variable is the field declaration from the service variables if any
+	Variable model.Variable
 }
 
 // NewCaller creates an instance of a caller
@@ -53,12 +69,12 @@ func (c *Caller) GetFields(endpointID string, service *model.Service) map[string
 		fields = c.MergeFields(fields, c.ParseFields(service.Main.ServiceURL))
 
 		// Get api key if auth is api_key
-		if service.Security.Scheme == "api_key" && !end.Public {
+		if service.Security.Scheme == "api_key" && !end.Public && len(service.Security.APIKey.Header) > 1 {
 			fields = c.MergeFields(fields, c.ParseFields(service.Security.APIKey.Header[1]))
 		}
 
 		// Get bearer token if auth is bearer
-		if service.Security.Scheme == "bearer" && !end.Public {
+		if service.Security.Scheme == "bearer" && !end.Public && len(service.Security.Bearer.Header) > 1 {
 			fields = c.MergeFields(fields, c.ParseFields(service.Security.Bearer.Header[1]))
 		}
 
@@ -73,76 +89,176 @@ func (c *Caller) GetFields(endpointID string, service *model.Service) map[string
 
 		// Get headers vars
 		for _, header := range end.Headers {
-			fields = c.MergeFields(fields, c.ParseFields(header[1]))
+			if len(header) > 1 {
+				fields = c.MergeFields(fields, c.ParseFields(header[1]))
+			}
 		}
 
 		// Get parameters vars
 		for _, parameter := range end.Parameters {
-			fields = c.MergeFields(fields, c.ParseFields(parameter[1]))
+			if len(parameter) > 1 {
+				fields = c.MergeFields(fields, c.ParseFields(parameter[1]))
+			}
 		}
 
 		// Get Body vars
 		fields = c.MergeFields(fields, c.ParseFields(end.Body))
+
+		// Get websocket messages vars
+		for _, message := range end.Messages {
+			fields = c.MergeFields(fields, c.ParseFields(message))
+		}
+
+		// Get form vars
+		for _, item := range end.Form {
+			fields = c.MergeFields(fields, c.ParseFields(item.Value))
+			fields = c.MergeFields(fields, c.ParseFields(item.File))
+		}
+
+		// Get graphql query and variables vars
+		fields = c.MergeFields(fields, c.ParseFields(end.Query))
+		fields = c.MergeFields(fields, c.ParseFields(end.OperationName))
+		fields = c.MergeFields(fields, c.graphQLVarsFields(end.Variables))
 	}
 
-	return fields
+	return c.DeclareFields(fields, service)
+}
+
+// LoadBodyFile loads the endpoint body or graphql query from their files if defined
+//
+// The file paths are relative to the service definition file.
+func (c *Caller) LoadBodyFile(endpointID string, service *model.Service) error {
+	for i, end := range service.Endpoint {
+		if fmt.Sprintf("%s - %s", service.Main.ID, end.ID) != endpointID {
+			continue
+		}
+
+		for _, item := range []struct {
+			Path   string
+			Target *string
+		}{
+			{end.BodyFile, &service.Endpoint[i].Body},
+			{end.QueryFile, &service.Endpoint[i].Query},
+		} {
+			if item.Path == "" {
+				continue
+			}
+
+			content, err := util.ReadFile(relativePath(service, item.Path))
+
+			if err != nil {
+				return err
+			}
+
+			*item.Target = content
+		}
+	}
+
+	return nil
+}
+
+// relativePath resolves a path relative to the service definition file
+func relativePath(service *model.Service, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(filepath.Dir(service.Path), path)
+}
+
+// GetEndpoint gets an endpoint by its ID
+func (c *Caller) GetEndpoint(endpointID string, service *model.Service) (model.Endpoint, bool) {
+	for _, end := range service.Endpoint {
+		if fmt.Sprintf("%s - %s", service.Main.ID, end.ID) == endpointID {
+			return end, true
+		}
+	}
+
+	return model.Endpoint{}, false
+}
+
+// AddHeader adds a header to the endpoint headers
+func (c *Caller) AddHeader(endpointID string, service *model.Service, key, value string) {
+	for i, end := range service.Endpoint {
+		if fmt.Sprintf("%s - %s", service.Main.ID, end.ID) != endpointID {
+			continue
+		}
+
+		service.Endpoint[i].Headers = append(service.Endpoint[i].Headers, []string{key, value})
+	}
+}
+
+// SetBody overrides the endpoint body
+func (c *Caller) SetBody(endpointID string, service *model.Service, body string) {
+	for i, end := range service.Endpoint {
+		if fmt.Sprintf("%s - %s", service.Main.ID, end.ID) != endpointID {
+			continue
+		}
+
+		service.Endpoint[i].Body = body
+	}
 }
 
 // ParseFields parses a string to fetch fields
+//
+// Invalid templates have no fields, their errors are reported once rendered.
 func (c *Caller) ParseFields(data string) map[string]Field {
-	var ita []string
-	var key string
-	var value string
-
-	m := regexp.MustCompile(`{\$(.*?)}`)
-	items := m.FindAllString(data, -1)
 	fields := make(map[string]Field)
+	tokens, err := Tokenize(data)
+
+	if err != nil {
+		return fields
+	}
+
+	c.tokenFields(tokens, fields)
+
+	return fields
+}
 
-	for _, item := range items {
+// tokenFields collects the fields of the vars in tokens and in functions arguments
+func (c *Caller) tokenFields(tokens []Token, fields map[string]Field) {
+	for _, token := range tokens {
+		if token.Kind != VarToken {
+			for _, arg := range token.Args {
+				c.tokenFields(arg, fields)
+			}
 
-		if !strings.HasPrefix(item, `{$`) || !strings.HasSuffix(item, "}") {
 			continue
 		}
 
-		if !strings.Contains(item, ":") {
-			// Incase the item in this format {$var}
-			item = strings.Replace(item, "$", "", -1)
-			item = strings.Replace(item, "{", "", -1)
-			item = strings.Replace(item, "}", "", -1)
-
-			fields[item] = Field{
-				Prompt:     fmt.Sprintf(`$%s%s (default=''):`, item, Red("*")),
+		if !token.Optional {
+			fields[token.Name] = Field{
+				Prompt:     fmt.Sprintf(`$%s%s (default=''):`, token.Name, Red("*")),
 				IsOptional: false,
 				Default:    "",
+				Variable:   model.Variable{Secret: token.Secret},
 			}
 			continue
 		}
-		// Incase the item in this format {$var:default} or {$var:something:complex}
-		// this will match and extract {$....: part
-		m = regexp.MustCompile(`{\$(.*?):`)
-		ita = m.FindAllString(item, -1)
-		key = strings.TrimPrefix(ita[0], `{$`)
-		key = strings.TrimSuffix(key, `:`)
-
-		// this will match and extract :.....} part
-		m = regexp.MustCompile(`:(.*?)}`)
-		ita = m.FindAllString(item, -1)
-		value = strings.TrimPrefix(ita[0], `:`)
-		value = strings.TrimSuffix(value, `}`)
-
-		fields[key] = Field{
-			Prompt:     fmt.Sprintf(`$%s (default='%s'):`, key, Yellow(value)),
+
+		shown := token.Default
+
+		if token.Secret {
+			shown = RedactedValue
+		}
+
+		fields[token.Name] = Field{
+			Prompt:     fmt.Sprintf(`$%s (default='%s'):`, token.Name, Yellow(shown)),
 			IsOptional: true,
-			Default:    value,
+			Default:    token.Default,
+			Variable:   model.Variable{Secret: token.Secret},
 		}
 	}
-
-	return fields
 }
 
 // MergeFields merges two fields list
 func (c *Caller) MergeFields(m1, m2 map[string]Field) map[string]Field {
 	for k, v := range m2 {
+		// A field marked as secret anywhere stays secret
+		if m1[k].IsSecret() {
+			v.Variable.Secret = true
+		}
+
 		m1[k] = v
 	}
 	return m1
@@ -150,147 +266,563 @@ func (c *Caller) MergeFields(m1, m2 map[string]Field) map[string]Field {
 
 // Call calls the remote service
 func (c *Caller) Call(endpointID string, service *model.Service, fields map[string]Field) (*http.Response, error) {
-	var response *http.Response
-	var err error
+	return c.CallWithContext(context.Background(), endpointID, service, fields)
+}
+
+// CallWithContext calls the remote service, the call is aborted once the context is done
+func (c *Caller) CallWithContext(ctx context.Context, endpointID string, service *model.Service, fields map[string]Field) (*http.Response, error) {
+	if strings.EqualFold(service.Main.Type, GRPCService) {
+		return nil, fmt.Errorf("Service %s is a grpc service", service.Main.ID)
+	}
 
 	for _, end := range service.Endpoint {
 		if fmt.Sprintf("%s - %s", service.Main.ID, end.ID) != endpointID {
 			continue
 		}
 
-		url := fmt.Sprintf(
-			"%s%s",
-			util.EnsureTrailingSlash(c.ReplaceVars(service.Main.ServiceURL, fields)),
-			util.RemoveStartingSlash(c.ReplaceVars(end.URI, fields)),
+		if strings.EqualFold(end.Type, WebSocketEndpoint) {
+			return nil, fmt.Errorf("Endpoint %s is a websocket endpoint", endpointID)
+		}
+
+		if end.Type != "" && !strings.EqualFold(end.Type, GraphQLEndpoint) {
+			return nil, fmt.Errorf("Invalid endpoint type %s", end.Type)
+		}
+
+		url, parameters, headers, err := c.Prepare(service, end, fields)
+
+		if err != nil {
+			return nil, err
+		}
+
+		bodyFields := fields
+
+		if IsJSONBody(end.Body, headers) {
+			bodyFields = JSONFields(fields)
+		}
+
+		data, err := c.Render(end.Body, bodyFields)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var body io.Reader
+		var contentType string
+
+		if strings.EqualFold(end.Type, GraphQLEndpoint) {
+			data, err = c.BuildGraphQL(end, fields)
+
+			if err != nil {
+				return nil, err
+			}
+
+			body, contentType = strings.NewReader(data), "application/json"
+
+			if end.Method == "" {
+				end.Method = http.MethodPost
+			}
+		} else {
+			body, contentType, err = c.BuildBody(end, data, fields)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if contentType != "" {
+			for k := range headers {
+				if strings.EqualFold(k, "Content-Type") {
+					delete(headers, k)
+				}
+			}
+			headers["Content-Type"] = contentType
+		}
+
+		return c.HTTPClient.RequestWithBody(
+			ctx,
+			end.Method,
+			url,
+			body,
+			parameters,
+			headers,
 		)
+	}
+
+	return nil, fmt.Errorf("Unable to find endpoint %s", endpointID)
+}
+
+// CallWebSocket opens a websocket session with the remote service
+func (c *Caller) CallWebSocket(ctx context.Context, endpointID string, service *model.Service, fields map[string]Field) (*WebSocketConn, *http.Response, error) {
+	end, ok := c.GetEndpoint(endpointID, service)
+
+	if !ok {
+		return nil, nil, fmt.Errorf("Unable to find endpoint %s", endpointID)
+	}
+
+	if !strings.EqualFold(end.Type, WebSocketEndpoint) {
+		return nil, nil, fmt.Errorf("Endpoint %s is not a websocket endpoint", endpointID)
+	}
+
+	url, parameters, headers, err := c.Prepare(service, end, fields)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c.HTTPClient.WebSocket(ctx, url, parameters, headers)
+}
+
+// CallGRPC calls a unary or server streaming method of a grpc service
+//
+// The endpoint method is the full method name and the body is the JSON request,
+// headers are sent as metadata. The handler gets each response as JSON.
+func (c *Caller) CallGRPC(ctx context.Context, endpointID string, service *model.Service, fields map[string]Field, max int, handler func(string) error) (*GRPCResult, error) {
+	end, ok := c.GetEndpoint(endpointID, service)
+
+	if !ok {
+		return nil, fmt.Errorf("Unable to find endpoint %s", endpointID)
+	}
+
+	if !strings.EqualFold(service.Main.Type, GRPCService) {
+		return nil, fmt.Errorf("Service %s is not a grpc service", service.Main.ID)
+	}
+
+	_, _, headers, err := c.Prepare(service, end, fields)
 
-		data := c.ReplaceVars(end.Body, fields)
-		parameters := make(map[string]string)
-		headers := make(map[string]string)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewGRPCClient()
+	client.ConnectTimeout = c.HTTPClient.ConnectTimeout
+
+	for _, path := range service.Main.Descriptors {
+		client.Descriptors = append(client.Descriptors, relativePath(service, path))
+	}
+
+	for _, path := range service.Main.ImportPaths {
+		client.ImportPaths = append(client.ImportPaths, relativePath(service, path))
+	}
+
+	if c.HTTPClient.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, c.HTTPClient.Timeout)
+		defer cancel()
+	}
+
+	target, err := c.Render(service.Main.ServiceURL, fields)
+
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := client.Dial(ctx, target)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	method, err := client.FindMethod(ctx, conn, c.ReplaceVars(end.Method, fields))
 
-		// add service global headers
-		for _, header := range service.Main.Headers {
-			headers[header[0]] = header[1]
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := c.Render(end.Body, JSONFields(fields))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Invoke(ctx, conn, method, request, headers, max, handler)
+}
+
+// WebSocketMessages gets the endpoint websocket messages with the vars and functions replaced
+func (c *Caller) WebSocketMessages(end model.Endpoint, fields map[string]Field) ([]string, error) {
+	messages := []string{}
+
+	for _, item := range end.Messages {
+		messageFields := fields
+
+		if IsJSONBody(item, nil) {
+			messageFields = JSONFields(fields)
 		}
 
-		// Add api key to headers if auth is api_key
-		if service.Security.Scheme == "api_key" && !end.Public {
-			headers[service.Security.APIKey.Header[0]] = c.ReplaceVars(service.Security.APIKey.Header[1], fields)
+		message, err := c.Render(item, messageFields)
+
+		if err != nil {
+			return nil, err
 		}
 
-		// Add bearer token to headers if auth is bearer
-		if service.Security.Scheme == "bearer" && !end.Public {
-			headers[service.Security.Bearer.Header[0]] = c.ReplaceVars(service.Security.Bearer.Header[1], fields)
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// Prepare resolves the endpoint URL, parameters and headers and configures the http client
+func (c *Caller) Prepare(service *model.Service, end model.Endpoint, fields map[string]Field) (string, map[string]string, map[string]string, error) {
+	c.service = service
+
+	if err := c.ValidateFields(service, fields); err != nil {
+		return "", nil, nil, err
+	}
+
+	for _, field := range fields {
+		if field.IsSecret() {
+			c.HTTPClient.Redactor.Add(field.Value)
 		}
+	}
 
-		// Add base64 of username & password if auth is basic
-		if service.Security.Scheme == "basic" && !end.Public {
-			username := c.ReplaceVars(service.Security.Basic.Username, fields)
-			password := c.ReplaceVars(service.Security.Basic.Password, fields)
+	var renderErr error
 
-			headers[service.Security.Basic.Header[0]] = strings.Replace(
-				service.Security.Basic.Header[1],
-				"base64(username:password)",
-				b64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password))),
-				-1,
-			)
+	// render replaces the vars and functions keeping the first error
+	render := func(data string) string {
+		value, err := c.Render(data, fields)
+
+		if err != nil && renderErr == nil {
+			renderErr = err
 		}
 
-		// Get headers vars
-		for _, header := range end.Headers {
-			headers[header[0]] = c.ReplaceVars(header[1], fields)
+		return value
+	}
+
+	url := fmt.Sprintf(
+		"%s%s",
+		util.EnsureTrailingSlash(render(service.Main.ServiceURL)),
+		util.RemoveStartingSlash(render(end.URI)),
+	)
+
+	parameters := make(map[string]string)
+	headers := make(map[string]string)
+
+	// add service global headers
+	for _, header := range service.Main.Headers {
+		headers[header[0]] = render(header[1])
+	}
+
+	// Add api key to headers if auth is api_key
+	if service.Security.Scheme == "api_key" && !end.Public {
+		headers[service.Security.APIKey.Header[0]] = render(service.Security.APIKey.Header[1])
+	}
+
+	// Add bearer token to headers if auth is bearer
+	if service.Security.Scheme == "bearer" && !end.Public {
+		headers[service.Security.Bearer.Header[0]] = render(service.Security.Bearer.Header[1])
+	}
+
+	// Add base64 of username & password if auth is basic
+	if service.Security.Scheme == "basic" && !end.Public {
+		username := render(service.Security.Basic.Username)
+		password := render(service.Security.Basic.Password)
+
+		headers[service.Security.Basic.Header[0]] = strings.Replace(
+			service.Security.Basic.Header[1],
+			"base64(username:password)",
+			b64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password))),
+			-1,
+		)
+	}
+
+	// Get headers vars
+	for _, header := range end.Headers {
+		headers[header[0]] = render(header[1])
+	}
+
+	// Get parameters vars
+	for _, parameter := range end.Parameters {
+		parameters[parameter[0]] = render(parameter[1])
+	}
+
+	if renderErr != nil {
+		return "", nil, nil, renderErr
+	}
+
+	err := c.SetTimeouts(service, end)
+
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	c.HTTPClient.Protocol = service.Main.Protocol
+
+	retry, err := c.GetRetryPolicy(service, end)
+
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	c.HTTPClient.Retry = retry
+
+	redirect := c.Redirect
+
+	if redirect == nil {
+		redirect, err = c.GetRedirectPolicy(service, end)
+
+		if err != nil {
+			return "", nil, nil, err
 		}
+	}
 
-		// Get parameters vars
-		for _, parameter := range end.Parameters {
-			parameters[parameter[0]] = c.ReplaceVars(parameter[1], fields)
+	c.HTTPClient.Redirect = redirect
+
+	return url, parameters, headers, nil
+}
+
+// ValidateFields validates the fields values against the service variables declarations
+func (c *Caller) ValidateFields(service *model.Service, fields map[string]Field) error {
+	if err := CheckVariables(service); err != nil {
+		return err
+	}
+
+	if err := CheckPairs(service); err != nil {
+		return err
+	}
+
+	for key, field := range fields {
+		variable, ok := service.Variables[key]
+
+		if !ok || field.Value == "" {
+			continue
+		}
+
+		field.Variable = variable
+
+		if err := field.Validate(field.Value); err != nil {
+			return fmt.Errorf("Invalid value of $%s: %s", key, err.Error())
 		}
+	}
 
-		timeout, err := strconv.Atoi(strings.Replace(service.Main.Timeout, "s", "", -1))
+	return nil
+}
+
+// SetTimeouts sets the http client timeouts, endpoint timeouts override the service timeouts
+func (c *Caller) SetTimeouts(service *model.Service, end model.Endpoint) error {
+	var err error
+
+	for _, item := range []struct {
+		Target   *time.Duration
+		Service  string
+		Endpoint string
+	}{
+		{&c.HTTPClient.Timeout, service.Main.Timeout, end.Timeout},
+		{&c.HTTPClient.ConnectTimeout, service.Main.ConnectTimeout, end.ConnectTimeout},
+		{&c.HTTPClient.ReadTimeout, service.Main.ReadTimeout, end.ReadTimeout},
+	} {
+		value := item.Service
+
+		if strings.TrimSpace(item.Endpoint) != "" {
+			value = item.Endpoint
+		}
+
+		*item.Target, err = util.ParseTimeout(value)
 
 		if err != nil {
-			return response, err
+			return err
 		}
+	}
+
+	if c.Timeout > 0 {
+		c.HTTPClient.Timeout = c.Timeout
+		return nil
+	}
 
-		c.HTTPClient.Timeout = time.Duration(timeout)
+	// Streams stay open and downloads may take long so the timeout only bounds
+	// waiting for the response headers, an endpoint timeout overrides a service
+	// read timeout
+	if end.Stream || end.Download || c.Download {
+		endpoint := strings.TrimSpace(end.Timeout) != "" && strings.TrimSpace(end.ReadTimeout) == ""
 
-		if end.Method == "get" {
-			response, err = c.HTTPClient.Get(
-				context.TODO(),
-				url,
-				parameters,
-				headers,
-			)
+		if c.HTTPClient.ReadTimeout <= 0 || endpoint {
+			c.HTTPClient.ReadTimeout = c.HTTPClient.Timeout
 		}
 
-		if end.Method == "post" {
-			response, err = c.HTTPClient.Post(
-				context.TODO(),
-				url,
-				data,
-				parameters,
-				headers,
-			)
+		c.HTTPClient.Timeout = 0
+	}
+
+	return nil
+}
+
+// GetRetryPolicy gets the endpoint retry policy, it falls back to the service retry policy
+func (c *Caller) GetRetryPolicy(service *model.Service, end model.Endpoint) (*RetryPolicy, error) {
+	retry := service.Main.Retry
+
+	if end.Retry.MaxAttempts > 0 {
+		retry = end.Retry
+	}
+
+	if retry.MaxAttempts <= 1 {
+		return nil, nil
+	}
+
+	policy := NewRetryPolicy()
+	policy.MaxAttempts = retry.MaxAttempts
+	policy.NetworkErrors = retry.NetworkErrors
+	policy.NonIdempotent = retry.NonIdempotent
+
+	if len(retry.Statuses) > 0 {
+		policy.Statuses = retry.Statuses
+	}
+
+	if retry.Backoff != "" {
+		backoff, err := time.ParseDuration(retry.Backoff)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid retry backoff %s: %s", retry.Backoff, err.Error())
 		}
 
-		if end.Method == "put" {
-			response, err = c.HTTPClient.Put(
-				context.TODO(),
-				url,
-				data,
-				parameters,
-				headers,
-			)
+		policy.Backoff = backoff
+	}
+
+	if retry.MaxBackoff != "" {
+		maxBackoff, err := time.ParseDuration(retry.MaxBackoff)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid retry max backoff %s: %s", retry.MaxBackoff, err.Error())
 		}
 
-		if end.Method == "delete" {
-			response, err = c.HTTPClient.Delete(
-				context.TODO(),
-				url,
-				parameters,
-				headers,
-			)
+		policy.MaxBackoff = maxBackoff
+	}
+
+	return policy, nil
+}
+
+// GetRedirectPolicy gets the endpoint redirect policy
+//
+// Auth headers of the service security scheme are dropped on redirects
+// to other hosts unless keep_auth is enabled.
+func (c *Caller) GetRedirectPolicy(service *model.Service, end model.Endpoint) (*RedirectPolicy, error) {
+	policy := NewRedirectPolicy()
+	policy.KeepAuth = end.Redirect.KeepAuth
+
+	switch strings.ToLower(end.Redirect.Policy) {
+	case "", "follow":
+		policy.Follow = true
+	case "none":
+		policy.Follow = false
+	default:
+		return nil, fmt.Errorf("Invalid redirect policy %s", end.Redirect.Policy)
+	}
+
+	if end.Redirect.MaxHops > 0 {
+		policy.MaxHops = end.Redirect.MaxHops
+	}
+
+	for _, header := range [][]string{
+		service.Security.APIKey.Header,
+		service.Security.Bearer.Header,
+		service.Security.Basic.Header,
+	} {
+		if len(header) > 0 && !util.InArray(header[0], policy.AuthHeaders) {
+			policy.AuthHeaders = append(policy.AuthHeaders, header[0])
 		}
+	}
+
+	return policy, nil
+}
+
+// BuildBody builds the request body based on the endpoint body type
+//
+// It returns the body and the content type to override the headers with if any.
+func (c *Caller) BuildBody(end model.Endpoint, data string, fields map[string]Field) (io.Reader, string, error) {
+	form := []FormField{}
+
+	for _, item := range end.Form {
+		value, err := c.Render(item.Value, fields)
 
-		if end.Method == "patch" {
-			response, err = c.HTTPClient.Patch(
-				context.TODO(),
-				url,
-				data,
-				parameters,
-				headers,
-			)
+		if err != nil {
+			return nil, "", err
 		}
+
+		form = append(form, FormField{
+			Name:  item.Name,
+			Value: value,
+			File:  c.ReplaceVars(item.File, fields),
+		})
+	}
+
+	switch strings.ToLower(end.BodyType) {
+	case "", RawBody:
+		if data == "" {
+			return http.NoBody, "", nil
+		}
+		return strings.NewReader(data), "", nil
+
+	case MultipartBody:
+		body, contentType := c.HTTPClient.Multipart(form)
+		return body, contentType, nil
+
+	case URLEncodedBody:
+		body, err := c.HTTPClient.URLEncoded(form)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		return strings.NewReader(body), "application/x-www-form-urlencoded", nil
+
+	case FileBody:
+		file, err := c.HTTPClient.File(data)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		return file, "", nil
+	}
+
+	return nil, "", fmt.Errorf("Invalid body type %s", end.BodyType)
+}
+
+// Render replaces the vars, resolves the secret references and evaluates the functions like {$uuid()}
+//
+// Values are inserted as is so placeholders in them are never evaluated. Resolved
+// secrets are redacted from the output.
+func (c *Caller) Render(data string, fields map[string]Field) (string, error) {
+	if c.secrets == nil {
+		c.secrets = make(map[string]string)
+	}
+
+	evaluator := &Evaluator{
+		Fields:    fields,
+		Functions: true,
+		Service:   c.service,
+		Secrets:   true,
+		Store:     c.Secrets,
+		Cache:     c.secrets,
+	}
+
+	result, err := evaluator.Render(data)
+
+	for _, value := range c.secrets {
+		c.HTTPClient.Redactor.Add(value)
 	}
 
 	if err != nil {
-		return response, err
+		return "", err
 	}
 
-	return response, nil
+	return result, nil
 }
 
-// ReplaceVars replaces vars
+// ReplaceVars replaces vars, functions and secret references are kept as written
 func (c *Caller) ReplaceVars(data string, fields map[string]Field) string {
-	for k, field := range fields {
-		if field.IsOptional {
-			data = strings.Replace(
-				data,
-				fmt.Sprintf("{$%s:%s}", k, field.Default),
-				field.Value,
-				-1,
-			)
-		} else {
-			data = strings.Replace(
-				data,
-				fmt.Sprintf("{$%s}", k),
-				field.Value,
-				-1,
-			)
-		}
+	evaluator := &Evaluator{Fields: fields}
+	result, err := evaluator.Render(data)
+
+	if err != nil {
+		return data
 	}
 
-	return data
+	return result
+}
+
+// Redact replaces the secret values in data
+func (c *Caller) Redact(data string) string {
+	return c.HTTPClient.Redactor.Redact(data)
 }
 
 // Pretty returns colored output
@@ -301,6 +833,64 @@ func (c *Caller) Pretty(response *http.Response) string {
 		body = fmt.Sprintf("Error %s", err.Error())
 	}
 
+	value := c.PrettyHeaders(response)
+
+	value = value + fmt.Sprintf("\n%s", Yellow(c.Redact(body)))
+
+	value = value + "\n" + c.PrettyProtocol(response)
+
+	if redirects := c.PrettyRedirects(response); redirects != "" {
+		value = value + "\n" + redirects
+	}
+
+	if c.Timing {
+		value = value + "\n" + c.PrettyTiming(response)
+	}
+
+	return value
+}
+
+// PrettyProtocol returns colored negotiated protocol and ALPN result of the request
+func (c *Caller) PrettyProtocol(response *http.Response) string {
+	alpn := "none (cleartext)"
+
+	if response.TLS != nil {
+		alpn = response.TLS.NegotiatedProtocol
+
+		if alpn == "" {
+			alpn = "none (not negotiated)"
+		}
+	} else if response.ProtoMajor == 2 {
+		alpn = "none (h2c prior knowledge)"
+	}
+
+	return fmt.Sprintf("%s %s\n%s %s\n", Cyan("Protocol:"), response.Proto, Cyan("ALPN:"), alpn)
+}
+
+// PrettyRedirects returns colored redirect chain of the request if any
+func (c *Caller) PrettyRedirects(response *http.Response) string {
+	chain, ok := GetRedirects(response)
+
+	if !ok {
+		return ""
+	}
+
+	return c.Redact(chain.Pretty())
+}
+
+// PrettyTiming returns colored timing breakdown of the request
+func (c *Caller) PrettyTiming(response *http.Response) string {
+	timing, ok := GetTiming(response)
+
+	if !ok {
+		return ""
+	}
+
+	return timing.Pretty()
+}
+
+// PrettyHeaders returns colored status line and headers
+func (c *Caller) PrettyHeaders(response *http.Response) string {
 	responseCode := c.HTTPClient.GetStatusCode(response)
 
 	value := "\n---\n"
@@ -314,11 +904,9 @@ func (c *Caller) Pretty(response *http.Response) string {
 
 	for k, v := range response.Header {
 		for _, h := range v {
-			value = value + fmt.Sprintf("%s: %s\n", Cyan(k), h)
+			value = value + fmt.Sprintf("%s: %s\n", Cyan(k), c.Redact(h))
 		}
 	}
 
-	value = value + fmt.Sprintf("\n%s", Yellow(body))
-
 	return value
 }
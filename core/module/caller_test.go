@@ -5,9 +5,16 @@
 package module
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/clivern/poodle/core/model"
 
@@ -141,3 +148,185 @@ func TestCallerDeleteRequest(t *testing.T) {
 		pkg.Expect(t, true, strings.Contains(body, "DELETE"))
 	})
 }
+
+// TestCallerFormRequest test cases
+func TestCallerFormRequest(t *testing.T) {
+	t.Run("TestCallerFormRequest", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "avatar.png")
+		pkg.Expect(t, nil, ioutil.WriteFile(path, []byte("PNG"), 0644))
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Header().Set("X-Content-Type", r.Header.Get("Content-Type"))
+			w.Write(body)
+		}))
+		defer srv.Close()
+
+		httpClient := NewHTTPClient()
+		caller := NewCaller(httpClient)
+		service := model.NewService("anything")
+		service.Main.ServiceURL = srv.URL
+		service.Endpoint[1].Body = ""
+		service.Endpoint[1].BodyType = "multipart"
+		service.Endpoint[1].Form = []model.FormField{
+			{Name: "name", Value: "{$name}"},
+			{Name: "avatar", File: "{$avatar}"},
+		}
+
+		endpointID := fmt.Sprintf("%s - %s", service.Main.ID, service.Endpoint[1].ID)
+		fields := caller.GetFields(endpointID, service)
+
+		pkg.Expect(t, false, fields["name"].IsOptional)
+		pkg.Expect(t, false, fields["avatar"].IsOptional)
+
+		fields["name"] = Field{Value: "poodle"}
+		fields["avatar"] = Field{Value: path}
+
+		res, err := caller.Call(endpointID, service, fields)
+
+		pkg.Expect(t, nil, err)
+
+		body, err := httpClient.ToString(res)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, true, strings.HasPrefix(httpClient.GetHeaderValue(res, "X-Content-Type"), "multipart/form-data"))
+		pkg.Expect(t, true, strings.Contains(body, `name="avatar"; filename="avatar.png"`))
+		pkg.Expect(t, true, strings.Contains(body, "Content-Type: image/png"))
+		pkg.Expect(t, true, strings.Contains(body, "poodle"))
+
+		service.Endpoint[1].BodyType = "urlencoded"
+		service.Endpoint[1].Form = []model.FormField{
+			{Name: "name", Value: "{$name}"},
+		}
+
+		res, err = caller.Call(endpointID, service, fields)
+
+		pkg.Expect(t, nil, err)
+
+		body, err = httpClient.ToString(res)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "application/x-www-form-urlencoded", httpClient.GetHeaderValue(res, "X-Content-Type"))
+		pkg.Expect(t, "name=poodle", body)
+
+		service.Endpoint[1].BodyType = "file"
+		service.Endpoint[1].Body = "{$avatar}"
+
+		res, err = caller.Call(endpointID, service, fields)
+
+		pkg.Expect(t, nil, err)
+
+		body, err = httpClient.ToString(res)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "PNG", body)
+
+		service.Endpoint[1].BodyType = "xml"
+
+		_, err = caller.Call(endpointID, service, fields)
+
+		pkg.Expect(t, true, err != nil)
+	})
+}
+
+// TestCallerBodyFile test cases
+func TestCallerBodyFile(t *testing.T) {
+	t.Run("TestCallerBodyFile", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		pkg.Expect(t, nil, os.MkdirAll(filepath.Join(dir, "payloads"), 0755))
+		pkg.Expect(t, nil, ioutil.WriteFile(
+			filepath.Join(dir, "payloads", "create_item.json"),
+			[]byte(`{"name":"{$name}","type":"{$type:default}"}`),
+			0644,
+		))
+
+		service := model.NewService("anything")
+		service.Endpoint[1].Body = ""
+		service.Endpoint[1].BodyFile = "payloads/create_item.json"
+		pkg.Expect(t, nil, service.Encode(filepath.Join(dir, "anything.toml")))
+
+		service = model.NewEmptyService("anything")
+		pkg.Expect(t, nil, service.Decode(filepath.Join(dir, "anything.toml")))
+
+		caller := NewCaller(NewHTTPClient())
+		endpointID := fmt.Sprintf("%s - %s", service.Main.ID, service.Endpoint[1].ID)
+
+		pkg.Expect(t, nil, caller.LoadBodyFile(endpointID, service))
+		pkg.Expect(t, `{"name":"{$name}","type":"{$type:default}"}`, service.Endpoint[1].Body)
+
+		fields := caller.GetFields(endpointID, service)
+
+		pkg.Expect(t, false, fields["name"].IsOptional)
+		pkg.Expect(t, "default", fields["type"].Default)
+
+		caller.SetBody(endpointID, service, `{"id":"{$id}"}`)
+		fields = caller.GetFields(endpointID, service)
+
+		_, found := fields["name"]
+
+		pkg.Expect(t, false, fields["id"].IsOptional)
+		pkg.Expect(t, false, found)
+
+		service.Endpoint[1].BodyFile = "payloads/missing.json"
+		pkg.Expect(t, true, caller.LoadBodyFile(endpointID, service) != nil)
+	})
+}
+
+// TestCallerTimeouts test cases
+func TestCallerTimeouts(t *testing.T) {
+	t.Run("TestCallerTimeouts", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+		}))
+		defer srv.Close()
+
+		httpClient := NewHTTPClient()
+		caller := NewCaller(httpClient)
+		service := model.NewService("anything")
+		service.Main.ServiceURL = srv.URL
+		service.Main.Timeout = "1m"
+		service.Main.ConnectTimeout = "5s"
+		service.Endpoint[0].Timeout = "50ms"
+
+		endpointID := fmt.Sprintf("%s - %s", service.Main.ID, service.Endpoint[0].ID)
+
+		pkg.Expect(t, nil, caller.SetTimeouts(service, service.Endpoint[0]))
+		pkg.Expect(t, 50*time.Millisecond, httpClient.Timeout)
+		pkg.Expect(t, 5*time.Second, httpClient.ConnectTimeout)
+		pkg.Expect(t, time.Duration(0), httpClient.ReadTimeout)
+
+		_, err := caller.Call(endpointID, service, map[string]Field{})
+		pkg.Expect(t, true, err != nil)
+
+		// Cancelled calls are aborted
+		service.Endpoint[0].Timeout = ""
+		ctx, cancel := context.WithCancel(context.Background())
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+		}()
+
+		start := time.Now()
+		_, err = caller.CallWithContext(ctx, endpointID, service, map[string]Field{})
+
+		pkg.Expect(t, true, err != nil)
+		pkg.Expect(t, context.Canceled, ctx.Err())
+		pkg.Expect(t, true, time.Since(start) < time.Second)
+
+		// Invalid timeouts
+		service.Main.Timeout = "30x"
+		_, err = caller.Call(endpointID, service, map[string]Field{})
+		pkg.Expect(t, true, err != nil)
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clivern/poodle/core/util"
+)
+
+// StoredCookie struct
+type StoredCookie struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// CookieJar struct
+type CookieJar struct {
+	Path   string
+	Stored []StoredCookie
+
+	jar *cookiejar.Jar
+	sync.Mutex
+}
+
+// CookieJarPath gets the cookie jar file of a service inside the config directory
+func CookieJarPath(configDir, serviceID string) string {
+	return filepath.Join(
+		configDir,
+		"cookies",
+		fmt.Sprintf("%s.json", strings.Replace(serviceID, "/", "__", -1)),
+	)
+}
+
+// NewCookieJar creates an instance of a persistent cookie jar and loads the stored cookies
+func NewCookieJar(path string) (*CookieJar, error) {
+	jar, _ := cookiejar.New(nil)
+
+	c := &CookieJar{
+		Path:   path,
+		Stored: []StoredCookie{},
+		jar:    jar,
+	}
+
+	if !util.FileExists(path) {
+		return c, nil
+	}
+
+	content, err := util.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stored := []StoredCookie{}
+
+	if err := json.Unmarshal([]byte(content), &stored); err != nil {
+		return nil, err
+	}
+
+	for _, item := range stored {
+		u, err := url.Parse(item.URL)
+
+		if err != nil || item.Cookie == nil {
+			continue
+		}
+
+		c.SetCookies(u, []*http.Cookie{item.Cookie})
+	}
+
+	return c, nil
+}
+
+// SetCookies stores the cookies received from a URL
+func (c *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.jar.SetCookies(u, cookies)
+
+	origin := fmt.Sprintf("%s://%s/", u.Scheme, u.Host)
+
+	for _, cookie := range cookies {
+		item := *cookie
+
+		// Store max age as an absolute expiry since it is relative to now
+		if item.MaxAge > 0 {
+			item.Expires = time.Now().Add(time.Duration(item.MaxAge) * time.Second)
+			item.MaxAge = 0
+		}
+
+		if item.Path == "" {
+			item.Path = "/"
+		}
+
+		kept := []StoredCookie{}
+
+		for _, stored := range c.Stored {
+			if stored.URL == origin && stored.Cookie.Name == item.Name && stored.Cookie.Path == item.Path {
+				continue
+			}
+			kept = append(kept, stored)
+		}
+
+		if item.MaxAge >= 0 && (item.Expires.IsZero() || item.Expires.After(time.Now())) {
+			kept = append(kept, StoredCookie{URL: origin, Cookie: &item})
+		}
+
+		c.Stored = kept
+	}
+}
+
+// Cookies gets the cookies to send to a URL
+func (c *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.jar.Cookies(u)
+}
+
+// List gets the stored cookies that are not expired
+func (c *CookieJar) List() []StoredCookie {
+	c.Lock()
+	defer c.Unlock()
+
+	items := []StoredCookie{}
+
+	for _, stored := range c.Stored {
+		if stored.Cookie.Expires.IsZero() || stored.Cookie.Expires.After(time.Now()) {
+			items = append(items, stored)
+		}
+	}
+
+	return items
+}
+
+// Save stores the cookies on disk, the file is readable by the owner only
+func (c *CookieJar) Save() error {
+	data, err := json.MarshalIndent(c.List(), "", "    ")
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := util.EnsureDir(filepath.Dir(c.Path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(c.Path, data, 0600)
+}
+
+// Clear removes the stored cookies
+func (c *CookieJar) Clear() error {
+	c.Lock()
+	c.Stored = []StoredCookie{}
+	c.jar, _ = cookiejar.New(nil)
+	c.Unlock()
+
+	if !util.FileExists(c.Path) {
+		return nil
+	}
+
+	return util.DeleteFile(c.Path)
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clivern/poodle/pkg"
+)
+
+// TestCookieJar test cases
+func TestCookieJar(t *testing.T) {
+	t.Run("TestCookieJar", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/login" {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123", MaxAge: 3600})
+				http.SetCookie(w, &http.Cookie{Name: "expired", Value: "old", MaxAge: -1})
+				return
+			}
+
+			cookie, err := r.Cookie("session")
+
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			w.Write([]byte(cookie.Value))
+		}))
+		defer srv.Close()
+
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		path := CookieJarPath(dir, "team/service")
+		pkg.Expect(t, filepath.Join(dir, "cookies", "team__service.json"), path)
+
+		jar, err := NewCookieJar(path)
+		pkg.Expect(t, nil, err)
+
+		httpClient := NewHTTPClient()
+		httpClient.Jar = jar
+
+		response, err := httpClient.Get(context.TODO(), srv.URL+"/login", map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+		httpClient.ToString(response)
+
+		pkg.Expect(t, 1, len(jar.List()))
+		pkg.Expect(t, nil, jar.Save())
+
+		fi, err := os.Stat(path)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, os.FileMode(0600), fi.Mode().Perm())
+
+		// A new client with the stored cookies is still logged in
+		jar, err = NewCookieJar(path)
+		pkg.Expect(t, nil, err)
+
+		httpClient = NewHTTPClient()
+		httpClient.Jar = jar
+
+		response, err = httpClient.Get(context.TODO(), srv.URL+"/me", map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+
+		body, _ := httpClient.ToString(response)
+
+		pkg.Expect(t, http.StatusOK, response.StatusCode)
+		pkg.Expect(t, "abc123", body)
+
+		pkg.Expect(t, nil, jar.Clear())
+		_, err = os.Stat(path)
+		pkg.Expect(t, true, os.IsNotExist(err))
+
+		response, err = httpClient.Get(context.TODO(), srv.URL+"/me", map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+		httpClient.ToString(response)
+
+		pkg.Expect(t, http.StatusUnauthorized, response.StatusCode)
+	})
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/clivern/poodle/core/util"
+)
+
+// DefaultFilename is used if the response has no usable filename
+const DefaultFilename = "download"
+
+// Progress struct
+type Progress struct {
+	Output  io.Writer
+	Offset  int64
+	Current int64
+	Total   int64
+	Started time.Time
+	printed time.Time
+}
+
+// NewProgress creates an instance of progress
+//
+// Offset is the size already on disk in case of a resumed download,
+// and total is -1 if the size is unknown.
+func NewProgress(output io.Writer, offset, total int64) *Progress {
+	return &Progress{
+		Output:  output,
+		Offset:  offset,
+		Current: offset,
+		Total:   total,
+		Started: time.Now(),
+	}
+}
+
+// Write tracks the written bytes and prints the progress from time to time
+func (p *Progress) Write(b []byte) (int, error) {
+	p.Current += int64(len(b))
+
+	if time.Since(p.printed) > 200*time.Millisecond {
+		p.Print()
+	}
+
+	return len(b), nil
+}
+
+// Print prints the current progress
+func (p *Progress) Print() {
+	p.printed = time.Now()
+
+	speed := int64(0)
+	elapsed := time.Since(p.Started).Seconds()
+
+	if elapsed > 0 {
+		speed = int64(float64(p.Current-p.Offset) / elapsed)
+	}
+
+	if p.Total <= 0 {
+		fmt.Fprintf(
+			p.Output,
+			"\r%s %s/s\033[K",
+			util.FormatBytes(p.Current),
+			util.FormatBytes(speed),
+		)
+		return
+	}
+
+	percent := int(p.Current * 100 / p.Total)
+
+	if percent > 100 {
+		percent = 100
+	}
+
+	fmt.Fprintf(
+		p.Output,
+		"\r[%-25s] %3d%% %s/%s %s/s\033[K",
+		strings.Repeat("=", percent/4),
+		percent,
+		util.FormatBytes(p.Current),
+		util.FormatBytes(p.Total),
+		util.FormatBytes(speed),
+	)
+}
+
+// Done prints the final progress
+func (p *Progress) Done() {
+	p.Print()
+	fmt.Fprintln(p.Output)
+}
+
+// Filename gets the file name from the Content-Disposition header or the request URL
+func (h *HTTPClient) Filename(response *http.Response) string {
+	name := ""
+
+	if _, params, err := mime.ParseMediaType(response.Header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+
+	if name == "" && response.Request != nil {
+		name = path.Base(response.Request.URL.Path)
+	}
+
+	// Never trust the server with directories
+	name = filepath.Base(strings.Replace(name, "\\", "/", -1))
+
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return DefaultFilename
+	}
+
+	return name
+}
+
+// Download streams the response body to a file
+//
+// If offset is not zero, a range request was sent and the body is appended
+// to the file in case the server responded with partial content.
+func (h *HTTPClient) Download(response *http.Response, path string, offset int64, output io.Writer) (int64, error) {
+	defer response.Body.Close()
+
+	// The file is already complete
+	if offset > 0 && response.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return offset, nil
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+
+	if offset > 0 && response.StatusCode == http.StatusPartialContent {
+		start := int64(-1)
+		fmt.Sscanf(response.Header.Get("Content-Range"), "bytes %d-", &start)
+
+		if start != offset {
+			return 0, fmt.Errorf(
+				"Invalid content range %s, expected to start at %d",
+				response.Header.Get("Content-Range"),
+				offset,
+			)
+		}
+
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	} else {
+		offset = 0
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer file.Close()
+
+	total := int64(-1)
+
+	if response.ContentLength >= 0 {
+		total = offset + response.ContentLength
+	}
+
+	progress := NewProgress(output, offset, total)
+	written, err := io.Copy(io.MultiWriter(file, progress), response.Body)
+	progress.Done()
+
+	if err != nil {
+		return offset + written, err
+	}
+
+	return offset + written, nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/pkg"
+)
+
+// TestFilename test cases
+func TestFilename(t *testing.T) {
+	t.Run("TestFilename", func(t *testing.T) {
+		httpClient := NewHTTPClient()
+		request, _ := http.NewRequest("GET", "http://127.0.0.1/exports/report.csv", nil)
+
+		response := &http.Response{Header: http.Header{}, Request: request}
+		pkg.Expect(t, "report.csv", httpClient.Filename(response))
+
+		response.Header.Set("Content-Disposition", `attachment; filename="items.zip"`)
+		pkg.Expect(t, "items.zip", httpClient.Filename(response))
+
+		response.Header.Set("Content-Disposition", `attachment; filename="../../etc/passwd"`)
+		pkg.Expect(t, "passwd", httpClient.Filename(response))
+
+		request, _ = http.NewRequest("GET", "http://127.0.0.1/", nil)
+		response = &http.Response{Header: http.Header{}, Request: request}
+		pkg.Expect(t, DefaultFilename, httpClient.Filename(response))
+	})
+}
+
+// TestDownload test cases
+func TestDownload(t *testing.T) {
+	t.Run("TestDownload", func(t *testing.T) {
+		content := strings.Repeat("poodle", 1000)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Disposition", `attachment; filename="report.txt"`)
+			http.ServeContent(w, r, "report.txt", time.Now(), strings.NewReader(content))
+		}))
+		defer srv.Close()
+
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "report.txt")
+		httpClient := NewHTTPClient()
+		output := &bytes.Buffer{}
+
+		response, err := httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+
+		size, err := httpClient.Download(response, path, 0, output)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, int64(len(content)), size)
+		pkg.Expect(t, true, strings.Contains(output.String(), "100%"))
+
+		data, _ := ioutil.ReadFile(path)
+		pkg.Expect(t, content, string(data))
+
+		// Resume a partial download
+		pkg.Expect(t, nil, ioutil.WriteFile(path, []byte(content[:1500]), 0644))
+
+		response, err = httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{"Range": "bytes=1500-"})
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, http.StatusPartialContent, response.StatusCode)
+
+		size, err = httpClient.Download(response, path, 1500, output)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, int64(len(content)), size)
+
+		data, _ = ioutil.ReadFile(path)
+		pkg.Expect(t, content, string(data))
+
+		// Already complete
+		response, err = httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{"Range": "bytes=6000-"})
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, http.StatusRequestedRangeNotSatisfiable, response.StatusCode)
+
+		size, err = httpClient.Download(response, path, 6000, output)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, int64(6000), size)
+
+		data, _ = ioutil.ReadFile(path)
+		pkg.Expect(t, content, string(data))
+	})
+
+	t.Run("TestDownloadTimeout", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "6")
+			w.WriteHeader(http.StatusOK)
+
+			for _, chunk := range []string{"po", "od", "le"} {
+				w.Write([]byte(chunk))
+				w.(http.Flusher).Flush()
+				time.Sleep(150 * time.Millisecond)
+			}
+		}))
+		defer srv.Close()
+
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		service := model.NewEmptyService("files")
+		service.Main.ID = "files"
+		service.Main.ServiceURL = srv.URL
+		service.Main.Timeout = "200ms"
+		service.Endpoint = []model.Endpoint{{ID: "Export", Method: "get", URI: "/", Public: true}}
+
+		caller := NewCaller(NewHTTPClient())
+		caller.Download = true
+
+		// The service timeout only bounds waiting for the response headers
+		response, err := caller.CallWithContext(context.TODO(), "files - Export", service, map[string]Field{})
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, time.Duration(0), caller.HTTPClient.Timeout)
+		pkg.Expect(t, 200*time.Millisecond, caller.HTTPClient.ReadTimeout)
+
+		path := filepath.Join(dir, "export.txt")
+		size, err := caller.HTTPClient.Download(response, path, 0, &bytes.Buffer{})
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, int64(6), size)
+
+		data, _ := ioutil.ReadFile(path)
+		pkg.Expect(t, "poodle", string(data))
+	})
+}
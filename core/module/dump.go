@@ -0,0 +1,198 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+)
+
+// MaxDumpBody is the max request body size to dump, larger bodies are streamed without dumping
+const MaxDumpBody = 1 << 20
+
+// RedactedValue replaces the value of redacted headers
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactHeaders are the headers redacted by default
+var DefaultRedactHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-API-KEY",
+}
+
+// Dumper struct
+type Dumper struct {
+	Output       io.Writer
+	Redact       []string
+	ResponseBody bool
+	// Redactor redacts secret values anywhere in the dump if set
+	Redactor *Redactor
+
+	sync.Mutex
+}
+
+// NewDumper creates an instance of dumper
+func NewDumper(output io.Writer, redact []string, responseBody bool) *Dumper {
+	return &Dumper{
+		Output:       output,
+		Redact:       redact,
+		ResponseBody: responseBody,
+	}
+}
+
+// Transport wraps a round tripper to dump requests and responses
+func (d *Dumper) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &dumpTransport{dumper: d, next: next}
+}
+
+// DumpRequest writes the outgoing request as sent
+func (d *Dumper) DumpRequest(req *http.Request) error {
+	// Detach from the request context to keep the dump out of the request trace
+	clone := req.Clone(context.Background())
+	clone.Header = d.redactHeaders(req.Header)
+	withBody := req.Body != nil && req.Body != http.NoBody && req.ContentLength > 0 && req.ContentLength <= MaxDumpBody
+
+	if withBody {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+
+		if err != nil {
+			return err
+		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		clone.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	data, err := httputil.DumpRequestOut(clone, withBody)
+
+	if err != nil {
+		return err
+	}
+
+	if !withBody && req.Body != nil && req.Body != http.NoBody {
+		data = append(data, []byte("[streamed body omitted]")...)
+	}
+
+	d.write("> ", data)
+
+	return nil
+}
+
+// DumpResponse writes the raw response
+func (d *Dumper) DumpResponse(resp *http.Response) error {
+	clone := *resp
+	clone.Header = d.redactHeaders(resp.Header)
+
+	// Streams may never end
+	withBody := d.ResponseBody && !IsStream(resp)
+
+	if withBody {
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if err != nil {
+			return err
+		}
+
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		clone.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	data, err := httputil.DumpResponse(&clone, withBody)
+
+	if err != nil {
+		return err
+	}
+
+	d.write("< ", data)
+
+	return nil
+}
+
+// IsRedacted checks if a header must be redacted
+func (d *Dumper) IsRedacted(header string) bool {
+	for _, item := range d.Redact {
+		if strings.EqualFold(strings.TrimSpace(item), header) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactHeaders returns a copy of the headers with redacted values
+func (d *Dumper) redactHeaders(headers http.Header) http.Header {
+	result := headers.Clone()
+
+	for key, values := range result {
+		if !d.IsRedacted(key) {
+			continue
+		}
+
+		for i := range values {
+			values[i] = RedactedValue
+		}
+	}
+
+	return result
+}
+
+// write writes the dump prefixing each line
+func (d *Dumper) write(prefix string, data []byte) {
+	d.Lock()
+	defer d.Unlock()
+
+	data = []byte(d.Redactor.Redact(string(data)))
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+
+	for scanner.Scan() {
+		fmt.Fprintf(d.Output, "%s%s\n", prefix, strings.TrimRight(scanner.Text(), "\r"))
+	}
+
+	fmt.Fprintln(d.Output)
+}
+
+// dumpTransport dumps requests and responses passing through
+type dumpTransport struct {
+	dumper *Dumper
+	next   http.RoundTripper
+}
+
+// RoundTrip dumps the request, sends it and dumps the response
+func (t *dumpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.dumper.DumpRequest(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+
+	if err != nil {
+		return resp, err
+	}
+
+	if err := t.dumper.DumpResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	return resp, nil
+}
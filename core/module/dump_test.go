@@ -0,0 +1,86 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clivern/poodle/pkg"
+)
+
+// TestDumper test cases
+func TestDumper(t *testing.T) {
+	t.Run("TestDumper", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t"})
+			w.Write(body)
+		}))
+		defer srv.Close()
+
+		output := &bytes.Buffer{}
+		httpClient := NewHTTPClient()
+		httpClient.Dumper = NewDumper(output, append(DefaultRedactHeaders, "X-Token"), true)
+
+		response, err := httpClient.Post(
+			context.TODO(),
+			srv.URL+"/item",
+			`{"name":"poodle"}`,
+			map[string]string{"limit": "10"},
+			map[string]string{
+				"Authorization": "Bearer abc123",
+				"x-token":       "xyz789",
+				"X-Request-Id":  "r-1",
+			},
+		)
+
+		pkg.Expect(t, nil, err)
+
+		body, err := httpClient.ToString(response)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, `{"name":"poodle"}`, body)
+
+		dump := output.String()
+
+		pkg.Expect(t, true, strings.Contains(dump, "> POST /item?limit=10 HTTP/1.1"))
+		pkg.Expect(t, true, strings.Contains(dump, "> Authorization: [REDACTED]"))
+		pkg.Expect(t, true, strings.Contains(dump, "> X-Token: [REDACTED]"))
+		pkg.Expect(t, true, strings.Contains(dump, "> X-Request-Id: r-1"))
+		pkg.Expect(t, true, strings.Contains(dump, `> {"name":"poodle"}`))
+		pkg.Expect(t, true, strings.Contains(dump, "< HTTP/1.1 200 OK"))
+		pkg.Expect(t, true, strings.Contains(dump, "< Set-Cookie: [REDACTED]"))
+		pkg.Expect(t, true, strings.Contains(dump, `< {"name":"poodle"}`))
+		pkg.Expect(t, false, strings.Contains(dump, "abc123"))
+		pkg.Expect(t, false, strings.Contains(dump, "xyz789"))
+		pkg.Expect(t, false, strings.Contains(dump, "s3cr3t"))
+
+		// Streamed bodies are sent but not dumped
+		output.Reset()
+		reader, contentType := httpClient.Multipart([]FormField{{Name: "name", Value: "poodle"}})
+
+		response, err = httpClient.RequestWithBody(
+			context.TODO(),
+			"post",
+			srv.URL,
+			reader,
+			map[string]string{},
+			map[string]string{"Content-Type": contentType},
+		)
+
+		pkg.Expect(t, nil, err)
+
+		body, _ = httpClient.ToString(response)
+
+		pkg.Expect(t, true, strings.Contains(body, "poodle"))
+		pkg.Expect(t, true, strings.Contains(output.String(), "[streamed body omitted]"))
+	})
+}
@@ -35,7 +35,7 @@ func NewFileSystem() *FileSystem {
 	}
 }
 
-// LoadFromLocal gets files from a local path
+// LoadFromLocal gets files with an extension from a local path and its subdirectories
 func (f *FileSystem) LoadFromLocal(basePath string, extension string) error {
 
 	f.Files = make(map[string]VFile)
@@ -46,13 +46,17 @@ func (f *FileSystem) LoadFromLocal(basePath string, extension string) error {
 			return err
 		}
 
+		if util.IsHiddenDir(basePath, path, info) {
+			return filepath.SkipDir
+		}
+
 		modifParsed, err := dateparse.ParseLocal(info.ModTime().String())
 
 		if err != nil {
 			return err
 		}
 
-		if basePath != path && !info.IsDir() && strings.Contains(path, extension) {
+		if basePath != path && !info.IsDir() && strings.HasSuffix(path, "."+extension) {
 			file := strings.Replace(path, util.EnsureTrailingSlash(basePath), "", -1)
 
 			content, err := util.ReadFile(path)
@@ -78,11 +82,18 @@ func (f *FileSystem) LoadFromLocal(basePath string, extension string) error {
 func (f *FileSystem) DumpLocally(basePath string) error {
 
 	for key, file := range f.Files {
-		handler, err := os.Create(fmt.Sprintf(
+		path := fmt.Sprintf(
 			"%s%s",
 			util.EnsureTrailingSlash(basePath),
 			key,
-		))
+		)
+
+		// Files may be synced from a subdirectory
+		if err := os.MkdirAll(filepath.Dir(path), 0775); err != nil {
+			return err
+		}
+
+		handler, err := os.Create(path)
 
 		if err != nil {
 			return err
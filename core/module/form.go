@@ -0,0 +1,127 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// RawBody sends the endpoint body as is
+	RawBody = "raw"
+	// MultipartBody sends the form fields as multipart/form-data
+	MultipartBody = "multipart"
+	// URLEncodedBody sends the form fields as application/x-www-form-urlencoded
+	URLEncodedBody = "urlencoded"
+	// FileBody sends the content of the file in the endpoint body
+	FileBody = "file"
+)
+
+// FormField struct
+type FormField struct {
+	Name  string
+	Value string
+	File  string
+}
+
+// Multipart streams the form fields as a multipart body, returns the body and its content type
+func (h *HTTPClient) Multipart(fields []FormField) (io.Reader, string) {
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	go func() {
+		writer.CloseWithError(h.writeMultipart(form, fields))
+	}()
+
+	return reader, form.FormDataContentType()
+}
+
+// writeMultipart writes the form fields and copies the files one by one
+func (h *HTTPClient) writeMultipart(form *multipart.Writer, fields []FormField) error {
+	for _, field := range fields {
+		if field.File == "" {
+			if err := form.WriteField(field.Name, field.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		file, err := os.Open(field.File)
+
+		if err != nil {
+			return err
+		}
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(
+			`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(field.Name),
+			escapeQuotes(filepath.Base(field.File)),
+		))
+		header.Set("Content-Type", h.ContentType(field.File))
+
+		part, err := form.CreatePart(header)
+
+		if err == nil {
+			_, err = io.Copy(part, file)
+		}
+
+		file.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return form.Close()
+}
+
+// URLEncoded encodes the form fields as application/x-www-form-urlencoded
+func (h *HTTPClient) URLEncoded(fields []FormField) (string, error) {
+	var items []string
+
+	for _, field := range fields {
+		if field.File != "" {
+			return "", fmt.Errorf("File field %s is not allowed in urlencoded body", field.Name)
+		}
+
+		items = append(items, fmt.Sprintf(
+			"%s=%s",
+			url.QueryEscape(field.Name),
+			url.QueryEscape(field.Value),
+		))
+	}
+
+	return strings.Join(items, "&"), nil
+}
+
+// File opens a file to be streamed as a request body
+func (h *HTTPClient) File(path string) (*os.File, error) {
+	return os.Open(strings.TrimSpace(path))
+}
+
+// ContentType guesses the content type of a file from its extension
+func (h *HTTPClient) ContentType(path string) string {
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+
+	if contentType == "" {
+		return "application/octet-stream"
+	}
+
+	return contentType
+}
+
+// escapeQuotes escapes quotes in multipart header values
+func escapeQuotes(s string) string {
+	return strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace(s)
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clivern/poodle/pkg"
+)
+
+// TestMultipart test cases
+func TestMultipart(t *testing.T) {
+	t.Run("TestMultipart", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "item.json")
+		pkg.Expect(t, nil, ioutil.WriteFile(path, []byte(`{"name":"poodle"}`), 0644))
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := r.ParseMultipartForm(1 << 20)
+
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			file, header, err := r.FormFile("item")
+
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			defer file.Close()
+			content, _ := ioutil.ReadAll(file)
+
+			w.Header().Set("X-Name", r.FormValue("name"))
+			w.Header().Set("X-Filename", header.Filename)
+			w.Header().Set("X-File-Type", header.Header.Get("Content-Type"))
+			w.Write(content)
+		}))
+		defer srv.Close()
+
+		httpClient := NewHTTPClient()
+		body, contentType := httpClient.Multipart([]FormField{
+			{Name: "name", Value: "poodle"},
+			{Name: "item", File: path},
+		})
+
+		pkg.Expect(t, true, strings.HasPrefix(contentType, "multipart/form-data; boundary="))
+
+		response, err := httpClient.RequestWithBody(
+			context.TODO(),
+			"post",
+			srv.URL,
+			body,
+			map[string]string{},
+			map[string]string{"Content-Type": contentType},
+		)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, http.StatusOK, httpClient.GetStatusCode(response))
+		pkg.Expect(t, "poodle", httpClient.GetHeaderValue(response, "X-Name"))
+		pkg.Expect(t, "item.json", httpClient.GetHeaderValue(response, "X-Filename"))
+		pkg.Expect(t, "application/json", httpClient.GetHeaderValue(response, "X-File-Type"))
+
+		content, err := httpClient.ToString(response)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, `{"name":"poodle"}`, content)
+
+		body, _ = httpClient.Multipart([]FormField{
+			{Name: "item", File: filepath.Join(dir, "missing.json")},
+		})
+
+		_, err = ioutil.ReadAll(body)
+		pkg.Expect(t, true, err != nil)
+	})
+}
+
+// TestURLEncoded test cases
+func TestURLEncoded(t *testing.T) {
+	t.Run("TestURLEncoded", func(t *testing.T) {
+		httpClient := NewHTTPClient()
+
+		body, err := httpClient.URLEncoded([]FormField{
+			{Name: "name", Value: "poodle & co"},
+			{Name: "type", Value: "a=b"},
+		})
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "name=poodle+%26+co&type=a%3Db", body)
+
+		_, err = httpClient.URLEncoded([]FormField{
+			{Name: "item", File: "/tmp/item.json"},
+		})
+
+		pkg.Expect(t, true, err != nil)
+	})
+}
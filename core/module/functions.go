@@ -0,0 +1,186 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	b64 "encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Function is a built-in function usable in placeholders like {$uuid()}
+type Function struct {
+	// Args is the number of arguments, the single argument of a one argument
+	// function is taken as is so it may contain commas
+	Args int
+	// Optional is the number of trailing arguments that can be omitted
+	Optional int
+	// Path marks a file path argument, a relative one is resolved from the service definition file
+	Path bool
+	Call func(args []string) (string, error)
+}
+
+// timeLayouts are the named layouts of the now function
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+	"TimeOnly":    "15:04:05",
+}
+
+// Functions are the built-in functions
+var Functions = map[string]Function{
+	"uuid": {
+		Call: func(args []string) (string, error) {
+			return NewUUID()
+		},
+	},
+	"now": {
+		Args:     1,
+		Optional: 1,
+		Call: func(args []string) (string, error) {
+			layout := time.RFC3339
+
+			if len(args) > 0 && args[0] != "" {
+				layout = args[0]
+
+				if value, ok := timeLayouts[layout]; ok {
+					layout = value
+				}
+			}
+
+			return time.Now().Format(layout), nil
+		},
+	},
+	"unix": {
+		Call: func(args []string) (string, error) {
+			return strconv.FormatInt(time.Now().Unix(), 10), nil
+		},
+	},
+	"unix_ms": {
+		Call: func(args []string) (string, error) {
+			return strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10), nil
+		},
+	},
+	"random_int": {
+		Args: 2,
+		Call: func(args []string) (string, error) {
+			min, err := strconv.ParseInt(args[0], 10, 64)
+
+			if err != nil {
+				return "", fmt.Errorf("Invalid min %s of random_int", args[0])
+			}
+
+			max, err := strconv.ParseInt(args[1], 10, 64)
+
+			if err != nil {
+				return "", fmt.Errorf("Invalid max %s of random_int", args[1])
+			}
+
+			if max < min {
+				return "", fmt.Errorf("Max %d of random_int is less than min %d", max, min)
+			}
+
+			n, err := rand.Int(rand.Reader, big.NewInt(max-min+1))
+
+			if err != nil {
+				return "", err
+			}
+
+			return strconv.FormatInt(min+n.Int64(), 10), nil
+		},
+	},
+	"env": {
+		Args: 1,
+		Call: func(args []string) (string, error) {
+			value, ok := os.LookupEnv(args[0])
+
+			if !ok {
+				return "", fmt.Errorf("Environment variable %s is not set", args[0])
+			}
+
+			return value, nil
+		},
+	},
+	"base64": {
+		Args: 1,
+		Call: func(args []string) (string, error) {
+			return b64.StdEncoding.EncodeToString([]byte(args[0])), nil
+		},
+	},
+	"sha256": {
+		Args: 1,
+		Call: func(args []string) (string, error) {
+			return fmt.Sprintf("%x", sha256.Sum256([]byte(args[0]))), nil
+		},
+	},
+	"file": {
+		Args: 1,
+		Path: true,
+		Call: func(args []string) (string, error) {
+			data, err := ioutil.ReadFile(args[0])
+
+			if err != nil {
+				return "", err
+			}
+
+			return string(data), nil
+		},
+	},
+}
+
+// NewUUID generates a random version 4 UUID
+func NewUUID() (string, error) {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// CallFunction calls a built-in function
+func CallFunction(name string, args []string) (string, error) {
+	function, ok := Functions[name]
+
+	if !ok {
+		names := []string{}
+
+		for k := range Functions {
+			names = append(names, k)
+		}
+
+		sort.Strings(names)
+
+		return "", fmt.Errorf("Unknown function %s, use one of %s", name, strings.Join(names, ", "))
+	}
+
+	if len(args) > function.Args || len(args) < function.Args-function.Optional {
+		return "", fmt.Errorf("Function %s expects %d arguments, got %d", name, function.Args, len(args))
+	}
+
+	return function.Call(args)
+}
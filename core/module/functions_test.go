@@ -0,0 +1,154 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/pkg"
+)
+
+// renderFunctions evaluates the function placeholders in data
+func renderFunctions(data string) (string, error) {
+	evaluator := &Evaluator{Functions: true}
+
+	return evaluator.Render(data)
+}
+
+// TestFunctions test cases
+func TestFunctions(t *testing.T) {
+	t.Run("TestFunctionsValues", func(t *testing.T) {
+		os.Setenv("POODLE_TEST_FUNCTION", "value")
+		defer os.Unsetenv("POODLE_TEST_FUNCTION")
+
+		value, err := renderFunctions(`{$uuid()}`)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, true, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(value))
+
+		value, err = renderFunctions(`{$now("RFC3339")}`)
+		pkg.Expect(t, nil, err)
+		_, err = time.Parse(time.RFC3339, value)
+		pkg.Expect(t, nil, err)
+
+		value, err = renderFunctions(`{$now("2006-01-02")}`)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, time.Now().Format("2006-01-02"), value)
+
+		value, err = renderFunctions(`{$unix()}`)
+		pkg.Expect(t, nil, err)
+		unix, err := strconv.ParseInt(value, 10, 64)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, true, unix >= time.Now().Unix()-1)
+
+		for i := 0; i < 20; i++ {
+			value, err = renderFunctions(`{$random_int(1, 3)}`)
+			pkg.Expect(t, nil, err)
+			pkg.Expect(t, true, value == "1" || value == "2" || value == "3")
+		}
+
+		value, err = renderFunctions(`{"home": "{$env("POODLE_TEST_FUNCTION")}", "auth": "{$base64(user:pass)}"}`)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, `{"home": "value", "auth": "dXNlcjpwYXNz"}`, value)
+
+		value, err = renderFunctions(`{$sha256("")}`)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", value)
+
+		value, err = renderFunctions(`{$base64({$sha256(a)})}`)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "Y2E5NzgxMTJjYTFiYmRjYWZhYzIzMWIzOWEyM2RjNGRhNzg2ZWZmODE0N2M0ZTcyYjk4MDc3ODVhZmVlNDhiYg==", value)
+
+		file, err := ioutil.TempFile("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.Remove(file.Name())
+
+		file.WriteString("content")
+		file.Close()
+
+		value, err = renderFunctions(`{$file("` + file.Name() + `")}`)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "content", value)
+
+		// Relative paths are resolved from the service definition file
+		service := &model.Service{Path: filepath.Join(filepath.Dir(file.Name()), "service.toml")}
+		evaluator := &Evaluator{Functions: true, Service: service}
+
+		value, err = evaluator.Render(`{$file("` + filepath.Base(file.Name()) + `")}`)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "content", value)
+	})
+
+	t.Run("TestFunctionsErrors", func(t *testing.T) {
+		_, err := renderFunctions(`{$unknown()}`)
+		pkg.Expect(t, true, strings.HasPrefix(err.Error(), "Error in {$unknown()}: Unknown function unknown, use one of base64, env"))
+
+		_, err = renderFunctions(`{$random_int(1)}`)
+		pkg.Expect(t, "Error in {$random_int(1)}: Function random_int expects 2 arguments, got 1", err.Error())
+
+		_, err = renderFunctions(`{$random_int(5, 1)}`)
+		pkg.Expect(t, "Error in {$random_int(5, 1)}: Max 1 of random_int is less than min 5", err.Error())
+
+		_, err = renderFunctions(`{$env("POODLE_TEST_MISSING")}`)
+		pkg.Expect(t, `Error in {$env("POODLE_TEST_MISSING")}: Environment variable POODLE_TEST_MISSING is not set`, err.Error())
+	})
+
+	t.Run("TestFunctionsFields", func(t *testing.T) {
+		service := &model.Service{}
+		service.Main.ID = "functions"
+		service.Main.ServiceURL = "http://127.0.0.1"
+		service.Endpoint = []model.Endpoint{{
+			ID:      "create",
+			Method:  "post",
+			URI:     "/items/{$id}",
+			Headers: [][]string{{"Idempotency-Key", "{$uuid()}"}, {"X-Time", `{$now("15:04")}`}},
+			Body:    `{"token": "{$sha256({$secret})}", "count": {$random_int(1,1)}}`,
+		}}
+
+		caller := NewCaller(NewHTTPClient())
+		fields := caller.GetFields("functions - create", service)
+		pkg.Expect(t, 2, len(fields))
+		pkg.Expect(t, false, fields["id"].IsOptional)
+		pkg.Expect(t, false, fields["secret"].IsOptional)
+
+		fields["id"] = Field{Value: "1"}
+		fields["secret"] = Field{Value: "a"}
+
+		url, _, headers, err := caller.Prepare(service, service.Endpoint[0], fields)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "http://127.0.0.1/items/1", url)
+		pkg.Expect(t, 36, len(headers["Idempotency-Key"]))
+		pkg.Expect(t, 5, len(headers["X-Time"]))
+
+		body, err := caller.Render(service.Endpoint[0].Body, fields)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, `{"token": "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb", "count": 1}`, body)
+
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		pkg.Expect(t, nil, ioutil.WriteFile(filepath.Join(dir, "token.txt"), []byte("t0ken"), 0600))
+
+		service.Path = filepath.Join(dir, "functions.toml")
+		service.Endpoint[0].Headers = [][]string{{"X-Token", `{$file("token.txt")}`}}
+
+		_, _, headers, err = caller.Prepare(service, service.Endpoint[0], fields)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "t0ken", headers["X-Token"])
+
+		service.Endpoint[0].Headers = [][]string{{"X-Missing", "{$missing()}"}}
+
+		_, _, _, err = caller.Prepare(service, service.Endpoint[0], fields)
+		pkg.Expect(t, true, strings.HasPrefix(err.Error(), "Error in {$missing()}: Unknown function missing"))
+	})
+}
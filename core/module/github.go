@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 )
 
@@ -56,10 +57,25 @@ type GistResponse struct {
 }
 
 // NewGithubClient creates an instance of github client
+//
+// The token is sent by an auth middleware scoped to the API host so it
+// never leaks to redirect targets.
 func NewGithubClient(httpClient *HTTPClient, apiURL, username, token string) Github {
 	client := Github{}
 
-	client.HTTPClient = httpClient
+	// Copy the http client to keep the token out of the caller's middlewares
+	authClient := *httpClient
+	host := ""
+
+	if u, err := url.Parse(apiURL); err == nil {
+		host = u.Host
+	}
+
+	authClient.Use(AuthMiddleware(host, map[string]string{
+		"Authorization": fmt.Sprintf("token %s", token),
+	}))
+
+	client.HTTPClient = &authClient
 	client.APIURL = apiURL
 
 	client.OAuth = OAuth{
@@ -76,7 +92,7 @@ func (g *Github) Check(ctx context.Context) (OAuth, error) {
 		ctx,
 		fmt.Sprintf("%s/users/%s", g.APIURL, g.OAuth.Username),
 		map[string]string{},
-		map[string]string{"Authorization": fmt.Sprintf("token %s", g.OAuth.Token)},
+		map[string]string{},
 	)
 
 	if err != nil {
@@ -84,9 +100,12 @@ func (g *Github) Check(ctx context.Context) (OAuth, error) {
 	}
 
 	if http.StatusOK != g.HTTPClient.GetStatusCode(response) {
+		g.HTTPClient.DrainAndClose(response)
 		return g.OAuth, fmt.Errorf("Invalid status code %d", g.HTTPClient.GetStatusCode(response))
 	}
 
+	g.HTTPClient.DrainAndClose(response)
+
 	g.OAuth.Scopes = g.HTTPClient.GetHeaderValue(response, "X-OAuth-Scopes")
 	g.OAuth.Valid = strings.Contains(g.OAuth.Scopes, "gist")
 
@@ -112,7 +131,7 @@ func (g *Github) CreateGist(ctx context.Context, gist Gist) (GistResponse, error
 		fmt.Sprintf("%s/gists", g.APIURL),
 		request,
 		map[string]string{},
-		map[string]string{"Authorization": fmt.Sprintf("token %s", g.OAuth.Token)},
+		map[string]string{},
 	)
 
 	if err != nil {
@@ -120,6 +139,7 @@ func (g *Github) CreateGist(ctx context.Context, gist Gist) (GistResponse, error
 	}
 
 	if http.StatusCreated != g.HTTPClient.GetStatusCode(response) {
+		g.HTTPClient.DrainAndClose(response)
 		return gistResponse, fmt.Errorf("Invalid status code %d", g.HTTPClient.GetStatusCode(response))
 	}
 
@@ -149,7 +169,7 @@ func (g *Github) UpdateGist(ctx context.Context, id string, gist Gist) (GistResp
 		fmt.Sprintf("%s/gists/%s", g.APIURL, id),
 		request,
 		map[string]string{},
-		map[string]string{"Authorization": fmt.Sprintf("token %s", g.OAuth.Token)},
+		map[string]string{},
 	)
 
 	if err != nil {
@@ -157,6 +177,7 @@ func (g *Github) UpdateGist(ctx context.Context, id string, gist Gist) (GistResp
 	}
 
 	if http.StatusOK != g.HTTPClient.GetStatusCode(response) {
+		g.HTTPClient.DrainAndClose(response)
 		return gistResponse, fmt.Errorf("Invalid status code %d", g.HTTPClient.GetStatusCode(response))
 	}
 
@@ -179,7 +200,7 @@ func (g *Github) GetGist(ctx context.Context, id string) (GistResponse, error) {
 		ctx,
 		fmt.Sprintf("%s/gists/%s", g.APIURL, id),
 		map[string]string{},
-		map[string]string{"Authorization": fmt.Sprintf("token %s", g.OAuth.Token)},
+		map[string]string{},
 	)
 
 	if err != nil {
@@ -187,6 +208,7 @@ func (g *Github) GetGist(ctx context.Context, id string) (GistResponse, error) {
 	}
 
 	if http.StatusOK != g.HTTPClient.GetStatusCode(response) {
+		g.HTTPClient.DrainAndClose(response)
 		return gistResponse, fmt.Errorf("Invalid status code %d", g.HTTPClient.GetStatusCode(response))
 	}
 
@@ -207,7 +229,7 @@ func (g *Github) DeleteGist(ctx context.Context, id string) (bool, error) {
 		ctx,
 		fmt.Sprintf("%s/gists/%s", g.APIURL, id),
 		map[string]string{},
-		map[string]string{"Authorization": fmt.Sprintf("token %s", g.OAuth.Token)},
+		map[string]string{},
 	)
 
 	if err != nil {
@@ -215,9 +237,12 @@ func (g *Github) DeleteGist(ctx context.Context, id string) (bool, error) {
 	}
 
 	if http.StatusNoContent != g.HTTPClient.GetStatusCode(response) {
+		g.HTTPClient.DrainAndClose(response)
 		return false, fmt.Errorf("Invalid status code %d", g.HTTPClient.GetStatusCode(response))
 	}
 
+	g.HTTPClient.DrainAndClose(response)
+
 	return true, nil
 }
 
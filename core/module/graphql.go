@@ -0,0 +1,531 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/clivern/poodle/core/util"
+
+	"github.com/clivern/poodle/core/model"
+)
+
+// graphQLVariable matches a variable definition of a graphql operation like $ids: [ID!]!
+var graphQLVariable = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)\s*:\s*([\[\]A-Za-z0-9_!\s]+)`)
+
+// GraphQLEndpoint is the type of graphql endpoints
+const GraphQLEndpoint = "graphql"
+
+// DefaultGraphQLURI is the graphql endpoint uri if the service has no graphql endpoints
+const DefaultGraphQLURI = "/graphql"
+
+// IntrospectionQuery fetches the root operations and the types of a graphql schema
+const IntrospectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    types {
+      kind
+      name
+      fields(includeDeprecated: false) {
+        name
+        description
+        args { name type { ...TypeRef } }
+        type { ...TypeRef }
+      }
+    }
+  }
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+}`
+
+// GraphQLRequest struct
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphQLError struct
+type GraphQLError struct {
+	Message string `json:"message"`
+}
+
+// GraphQLTypeRef struct
+type GraphQLTypeRef struct {
+	Kind   string          `json:"kind"`
+	Name   string          `json:"name"`
+	OfType *GraphQLTypeRef `json:"ofType"`
+}
+
+// GraphQLInputValue struct
+type GraphQLInputValue struct {
+	Name string         `json:"name"`
+	Type GraphQLTypeRef `json:"type"`
+}
+
+// GraphQLField struct
+type GraphQLField struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Args        []GraphQLInputValue `json:"args"`
+	Type        GraphQLTypeRef      `json:"type"`
+}
+
+// GraphQLType struct
+type GraphQLType struct {
+	Kind   string         `json:"kind"`
+	Name   string         `json:"name"`
+	Fields []GraphQLField `json:"fields"`
+}
+
+// GraphQLSchema struct
+type GraphQLSchema struct {
+	QueryType    *GraphQLTypeRef `json:"queryType"`
+	MutationType *GraphQLTypeRef `json:"mutationType"`
+	Types        []GraphQLType   `json:"types"`
+}
+
+// BuildGraphQL wraps the endpoint query and variables into a graphql JSON envelope
+//
+// A variable that is exactly one placeholder, like "{$limit:10}", gets its
+// value decoded as JSON if possible so that numbers, booleans, null, lists
+// and objects keep their type. Other values are sent as strings.
+func (c *Caller) BuildGraphQL(end model.Endpoint, fields map[string]Field) (string, error) {
+	query, err := c.Render(end.Query, fields)
+
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(query) == "" {
+		return "", fmt.Errorf("GraphQL endpoint %s has no query", end.ID)
+	}
+
+	request := GraphQLRequest{
+		Query:         query,
+		OperationName: c.ReplaceVars(end.OperationName, fields),
+	}
+
+	if len(end.Variables) > 0 {
+		types := GraphQLVariableTypes(query)
+		request.Variables = make(map[string]interface{})
+
+		for name, item := range end.Variables {
+			value, err := c.replaceGraphQLVars(item, fields, types[name])
+
+			if err != nil {
+				return "", err
+			}
+
+			request.Variables[name] = value
+		}
+	}
+
+	data, err := json.Marshal(request)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// replaceGraphQLVars replaces the vars and functions in graphql variables recursively
+//
+// A value made of a single placeholder is decoded as JSON if its variable is
+// declared as int, float, bool or json or if its graphql type is not a String
+// or an ID, so numbers typed for an ID! argument are still sent as strings.
+func (c *Caller) replaceGraphQLVars(value interface{}, fields map[string]Field, kind string) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		result, err := c.Render(v, fields)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if result == v {
+			return result, nil
+		}
+
+		// Unset nullable arguments are sent as null whatever their type
+		if result == "null" && isNullDefault(v) {
+			return nil, nil
+		}
+
+		if !c.isTypedGraphQLVar(v, fields, kind) {
+			return result, nil
+		}
+
+		var typed interface{}
+
+		if err := json.Unmarshal([]byte(result), &typed); err == nil {
+			return typed, nil
+		}
+
+		return result, nil
+	case map[string]interface{}:
+		items := make(map[string]interface{})
+
+		for k, item := range v {
+			value, err := c.replaceGraphQLVars(item, fields, "")
+
+			if err != nil {
+				return nil, err
+			}
+
+			items[k] = value
+		}
+
+		return items, nil
+	case []interface{}:
+		return c.replaceGraphQLList(v, fields, graphQLItemType(kind))
+	case []map[string]interface{}:
+		list := make([]interface{}, len(v))
+
+		for i, item := range v {
+			list[i] = item
+		}
+
+		return c.replaceGraphQLList(list, fields, "")
+	}
+
+	return value, nil
+}
+
+// replaceGraphQLList replaces the vars and functions in a list of graphql variables
+func (c *Caller) replaceGraphQLList(list []interface{}, fields map[string]Field, kind string) (interface{}, error) {
+	items := make([]interface{}, len(list))
+
+	for i, item := range list {
+		value, err := c.replaceGraphQLVars(item, fields, kind)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items[i] = value
+	}
+
+	return items, nil
+}
+
+// isTypedGraphQLVar checks if a single placeholder value holds a typed value
+// like a number, a boolean or a JSON document
+func (c *Caller) isTypedGraphQLVar(value string, fields map[string]Field, kind string) bool {
+	if IsPlaceholder(value, VarToken) {
+		tokens, err := Tokenize(value)
+
+		if err == nil && util.InArray(fields[tokens[0].Name].Variable.Type, []string{IntVariable, FloatVariable, BoolVariable, JSONVariable}) {
+			return true
+		}
+	} else if !IsPlaceholder(value, FunctionToken) {
+		return false
+	}
+
+	named := strings.TrimSuffix(kind, "!")
+
+	return named != "" && named != "String" && named != "ID"
+}
+
+// isNullDefault checks if a value is a single placeholder defaulting to null like {$after:null}
+func isNullDefault(value string) bool {
+	tokens, err := Tokenize(value)
+
+	return err == nil && len(tokens) == 1 && tokens[0].Kind == VarToken && tokens[0].Optional && tokens[0].Default == "null"
+}
+
+// GraphQLVariableTypes gets the types of the variables defined by a graphql query like [ID!]!
+func GraphQLVariableTypes(query string) map[string]string {
+	types := make(map[string]string)
+
+	for _, match := range graphQLVariable.FindAllStringSubmatch(query, -1) {
+		types[match[1]] = strings.Join(strings.Fields(match[2]), "")
+	}
+
+	return types
+}
+
+// graphQLItemType gets the type of the items of a graphql list type
+func graphQLItemType(kind string) string {
+	kind = strings.TrimSuffix(kind, "!")
+
+	if !strings.HasPrefix(kind, "[") || !strings.HasSuffix(kind, "]") {
+		return ""
+	}
+
+	return kind[1 : len(kind)-1]
+}
+
+// graphQLVarsFields gets the fields used in graphql variables recursively
+func (c *Caller) graphQLVarsFields(value interface{}) map[string]Field {
+	fields := make(map[string]Field)
+
+	switch v := value.(type) {
+	case string:
+		fields = c.ParseFields(v)
+	case map[string]interface{}:
+		for _, item := range v {
+			fields = c.MergeFields(fields, c.graphQLVarsFields(item))
+		}
+	case []interface{}:
+		for _, item := range v {
+			fields = c.MergeFields(fields, c.graphQLVarsFields(item))
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			fields = c.MergeFields(fields, c.graphQLVarsFields(item))
+		}
+	}
+
+	return fields
+}
+
+// ParseGraphQLSchema parses the response of the introspection query
+func ParseGraphQLSchema(data []byte) (*GraphQLSchema, error) {
+	var response struct {
+		Data struct {
+			Schema *GraphQLSchema `json:"__schema"`
+		} `json:"data"`
+		Errors []GraphQLError `json:"errors"`
+	}
+
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("Invalid introspection response: %s", err.Error())
+	}
+
+	if len(response.Errors) > 0 {
+		messages := []string{}
+
+		for _, item := range response.Errors {
+			messages = append(messages, item.Message)
+		}
+
+		return nil, fmt.Errorf("Introspection failed: %s", strings.Join(messages, "; "))
+	}
+
+	if response.Data.Schema == nil {
+		return nil, fmt.Errorf("Introspection response has no schema")
+	}
+
+	return response.Data.Schema, nil
+}
+
+// String returns the type in graphql notation like [ID!]!
+func (t GraphQLTypeRef) String() string {
+	switch t.Kind {
+	case "NON_NULL":
+		if t.OfType != nil {
+			return t.OfType.String() + "!"
+		}
+	case "LIST":
+		if t.OfType != nil {
+			return "[" + t.OfType.String() + "]"
+		}
+	}
+
+	return t.Name
+}
+
+// Named unwraps lists and non null wrappers of the type
+func (t GraphQLTypeRef) Named() GraphQLTypeRef {
+	for (t.Kind == "NON_NULL" || t.Kind == "LIST") && t.OfType != nil {
+		t = *t.OfType
+	}
+
+	return t
+}
+
+// GetType gets a type by its name
+func (s *GraphQLSchema) GetType(name string) (GraphQLType, bool) {
+	for _, item := range s.Types {
+		if item.Name == name {
+			return item, true
+		}
+	}
+
+	return GraphQLType{}, false
+}
+
+// Endpoints generates graphql endpoints for each query and mutation of the schema
+func (s *GraphQLSchema) Endpoints(uri string) []model.Endpoint {
+	endpoints := []model.Endpoint{}
+
+	for _, root := range []struct {
+		Operation string
+		Label     string
+		Type      *GraphQLTypeRef
+	}{
+		{"query", "Query", s.QueryType},
+		{"mutation", "Mutation", s.MutationType},
+	} {
+		if root.Type == nil {
+			continue
+		}
+
+		rootType, ok := s.GetType(root.Type.Name)
+
+		if !ok {
+			continue
+		}
+
+		for _, field := range rootType.Fields {
+			endpoints = append(endpoints, s.endpoint(root.Operation, root.Label, uri, field))
+		}
+	}
+
+	return endpoints
+}
+
+// endpoint generates a graphql endpoint for a root field
+func (s *GraphQLSchema) endpoint(operation, label, uri string, field GraphQLField) model.Endpoint {
+	definitions := []string{}
+	arguments := []string{}
+	variables := make(map[string]interface{})
+
+	for _, arg := range field.Args {
+		definitions = append(definitions, fmt.Sprintf("$%s: %s", arg.Name, arg.Type.String()))
+		arguments = append(arguments, fmt.Sprintf("%s: $%s", arg.Name, arg.Name))
+
+		if arg.Type.Kind == "NON_NULL" {
+			variables[arg.Name] = fmt.Sprintf("{$%s}", arg.Name)
+		} else {
+			variables[arg.Name] = fmt.Sprintf("{$%s:null}", arg.Name)
+		}
+	}
+
+	query := fmt.Sprintf("%s %s", operation, field.Name)
+
+	if len(definitions) > 0 {
+		query = fmt.Sprintf("%s(%s)", query, strings.Join(definitions, ", "))
+	}
+
+	query = fmt.Sprintf("%s {\n  %s", query, field.Name)
+
+	if len(arguments) > 0 {
+		query = fmt.Sprintf("%s(%s)", query, strings.Join(arguments, ", "))
+	}
+
+	if selection := s.selection(field.Type); len(selection) > 0 {
+		query = fmt.Sprintf("%s {\n    %s\n  }", query, strings.Join(selection, "\n    "))
+	}
+
+	return model.Endpoint{
+		ID:          field.Name,
+		Name:        fmt.Sprintf("%s %s", label, field.Name),
+		Description: field.Description,
+		Type:        GraphQLEndpoint,
+		Method:      http.MethodPost,
+		URI:         uri,
+		Query:       query + "\n}\n",
+		Variables:   variables,
+	}
+}
+
+// selection gets the scalar fields to select from a type
+func (s *GraphQLSchema) selection(ref GraphQLTypeRef) []string {
+	named := ref.Named()
+
+	switch named.Kind {
+	case "SCALAR", "ENUM":
+		return []string{}
+	case "OBJECT", "INTERFACE":
+		fields := []string{}
+		item, _ := s.GetType(named.Name)
+
+		for _, field := range item.Fields {
+			kind := field.Type.Named().Kind
+
+			if (kind == "SCALAR" || kind == "ENUM") && !hasRequiredArgs(field) {
+				fields = append(fields, field.Name)
+			}
+		}
+
+		if len(fields) > 0 {
+			return fields
+		}
+	}
+
+	return []string{"__typename"}
+}
+
+// hasRequiredArgs checks if a field can't be selected without arguments
+func hasRequiredArgs(field GraphQLField) bool {
+	for _, arg := range field.Args {
+		if arg.Type.Kind == "NON_NULL" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RenderEndpoints renders graphql endpoints as service definition stubs
+func RenderEndpoints(endpoints []model.Endpoint) (string, error) {
+	buf := new(bytes.Buffer)
+
+	for _, end := range endpoints {
+		if strings.Contains(end.Query, "'''") {
+			return "", fmt.Errorf("Unable to render the query of endpoint %s", end.ID)
+		}
+
+		fmt.Fprintf(buf, "[[Endpoint]]\n")
+
+		for _, item := range [][]string{
+			{"id", end.ID},
+			{"name", end.Name},
+			{"description", end.Description},
+			{"type", end.Type},
+			{"method", end.Method},
+			{"uri", end.URI},
+		} {
+			fmt.Fprintf(buf, "    %s = %s\n", item[0], quoteTOML(item[1]))
+		}
+
+		// Multi-line literal strings keep the query readable
+		fmt.Fprintf(buf, "    query = '''\n%s'''\n", end.Query)
+
+		if len(end.Variables) > 0 {
+			names := []string{}
+
+			for name := range end.Variables {
+				names = append(names, name)
+			}
+
+			sort.Strings(names)
+
+			fmt.Fprintf(buf, "    [Endpoint.variables]\n")
+
+			for _, name := range names {
+				fmt.Fprintf(buf, "        %s = %s\n", name, quoteTOML(fmt.Sprintf("%v", end.Variables[name])))
+			}
+		}
+
+		fmt.Fprintf(buf, "\n")
+	}
+
+	return buf.String(), nil
+}
+
+// quoteTOML quotes a value as a TOML basic string, JSON escapes are valid in TOML
+func quoteTOML(value string) string {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
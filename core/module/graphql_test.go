@@ -0,0 +1,246 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/pkg"
+)
+
+// introspectionResponse is a trimmed introspection result of a small schema
+const introspectionResponse = `{"data":{"__schema":{
+	"queryType":{"name":"Query"},
+	"mutationType":{"name":"Mutation"},
+	"types":[
+		{"kind":"OBJECT","name":"Query","fields":[
+			{"name":"user","description":"Get a user","args":[
+				{"name":"id","type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"SCALAR","name":"ID","ofType":null}}}
+			],"type":{"kind":"OBJECT","name":"User","ofType":null}},
+			{"name":"version","description":"","args":[],"type":{"kind":"SCALAR","name":"String","ofType":null}}
+		]},
+		{"kind":"OBJECT","name":"Mutation","fields":[
+			{"name":"createUser","description":"","args":[
+				{"name":"name","type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"SCALAR","name":"String","ofType":null}}},
+				{"name":"tags","type":{"kind":"LIST","name":null,"ofType":{"kind":"SCALAR","name":"String","ofType":null}}}
+			],"type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"OBJECT","name":"User","ofType":null}}}
+		]},
+		{"kind":"OBJECT","name":"User","fields":[
+			{"name":"id","description":"","args":[],"type":{"kind":"NON_NULL","name":null,"ofType":{"kind":"SCALAR","name":"ID","ofType":null}}},
+			{"name":"name","description":"","args":[],"type":{"kind":"SCALAR","name":"String","ofType":null}},
+			{"name":"friends","description":"","args":[],"type":{"kind":"LIST","name":null,"ofType":{"kind":"OBJECT","name":"User","ofType":null}}}
+		]}
+	]}}}`
+
+// TestGraphQL test cases
+func TestGraphQL(t *testing.T) {
+	t.Run("TestBuildGraphQL", func(t *testing.T) {
+		caller := NewCaller(NewHTTPClient())
+
+		end := model.Endpoint{
+			ID:    "users",
+			Query: "query Users($limit: Int) { users(limit: $limit, name: \"{$name}\") { id } }",
+			Variables: map[string]interface{}{
+				"limit":  "{$limit:10}",
+				"active": true,
+				"filter": map[string]interface{}{
+					"role": "{$role:admin}",
+				},
+			},
+		}
+
+		fields := caller.MergeFields(caller.ParseFields(end.Query), caller.graphQLVarsFields(end.Variables))
+		pkg.Expect(t, 3, len(fields))
+
+		fields["name"] = Field{Value: "joe"}
+		fields["limit"] = Field{IsOptional: true, Default: "10", Value: "20"}
+		fields["role"] = Field{IsOptional: true, Default: "admin", Value: "admin"}
+
+		data, err := caller.BuildGraphQL(end, fields)
+		pkg.Expect(t, nil, err)
+
+		request := GraphQLRequest{}
+		pkg.Expect(t, nil, json.Unmarshal([]byte(data), &request))
+
+		pkg.Expect(t, "query Users($limit: Int) { users(limit: $limit, name: \"joe\") { id } }", request.Query)
+		pkg.Expect(t, float64(20), request.Variables["limit"])
+		pkg.Expect(t, true, request.Variables["active"])
+		pkg.Expect(t, map[string]interface{}{"role": "admin"}, request.Variables["filter"])
+
+		_, err = caller.BuildGraphQL(model.Endpoint{ID: "empty"}, fields)
+		pkg.Expect(t, true, err != nil)
+	})
+
+	t.Run("TestGraphQLVariableTypes", func(t *testing.T) {
+		caller := NewCaller(NewHTTPClient())
+
+		end := model.Endpoint{
+			ID:    "user",
+			Query: "query User($id: ID!, $name: String\n $ids: [Int!]! = [1], $active: Boolean) { user(id: $id, name: $name) { id } }",
+			Variables: map[string]interface{}{
+				"id":      "{$id}",
+				"name":    "{$name}",
+				"ids":     []interface{}{"{$first}", "{$second}"},
+				"active":  "{$active}",
+				"private": "{$private}",
+				"count":   "{$count}",
+			},
+		}
+
+		pkg.Expect(t, map[string]string{"id": "ID!", "name": "String", "ids": "[Int!]!", "active": "Boolean"}, GraphQLVariableTypes(end.Query))
+
+		fields := map[string]Field{
+			"id":      {Value: "12345"},
+			"name":    {Value: "true"},
+			"first":   {Value: "1"},
+			"second":  {Value: "2"},
+			"active":  {Value: "false"},
+			"private": {Value: "true"},
+			"count":   {Value: "3", Variable: model.Variable{Type: IntVariable}},
+		}
+
+		data, err := caller.BuildGraphQL(end, fields)
+		pkg.Expect(t, nil, err)
+
+		request := GraphQLRequest{}
+		pkg.Expect(t, nil, json.Unmarshal([]byte(data), &request))
+
+		pkg.Expect(t, "12345", request.Variables["id"])
+		pkg.Expect(t, "true", request.Variables["name"])
+		pkg.Expect(t, []interface{}{float64(1), float64(2)}, request.Variables["ids"])
+		pkg.Expect(t, false, request.Variables["active"])
+		pkg.Expect(t, "true", request.Variables["private"])
+		pkg.Expect(t, float64(3), request.Variables["count"])
+	})
+
+	t.Run("TestGraphQLNullableString", func(t *testing.T) {
+		caller := NewCaller(NewHTTPClient())
+
+		end := model.Endpoint{
+			ID:    "users",
+			Query: "query Users($after: String, $before: String, $first: Int) { users(after: $after, before: $before, first: $first) { id } }",
+			Variables: map[string]interface{}{
+				"after":  "{$after:null}",
+				"before": "{$before:null}",
+				"first":  "{$first:null}",
+			},
+		}
+
+		fields := map[string]Field{
+			"after":  {IsOptional: true, Default: "null", Value: "null"},
+			"before": {IsOptional: true, Default: "null", Value: "abc"},
+			"first":  {IsOptional: true, Default: "null", Value: "null"},
+		}
+
+		data, err := caller.BuildGraphQL(end, fields)
+		pkg.Expect(t, nil, err)
+
+		request := GraphQLRequest{}
+		pkg.Expect(t, nil, json.Unmarshal([]byte(data), &request))
+
+		value, ok := request.Variables["after"]
+		pkg.Expect(t, true, ok)
+		pkg.Expect(t, nil, value)
+		pkg.Expect(t, "abc", request.Variables["before"])
+		pkg.Expect(t, nil, request.Variables["first"])
+	})
+
+	t.Run("TestCallGraphQL", func(t *testing.T) {
+		var request GraphQLRequest
+		contentType := ""
+		method := ""
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method = r.Method
+			contentType = r.Header.Get("Content-Type")
+			json.NewDecoder(r.Body).Decode(&request)
+			w.Write([]byte(`{"data":{"user":{"id":"1"}}}`))
+		}))
+		defer srv.Close()
+
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		err = ioutil.WriteFile(filepath.Join(dir, "user.graphql"), []byte("query User($id: ID!) { user(id: $id) { id } }"), 0600)
+		pkg.Expect(t, nil, err)
+
+		service := &model.Service{Path: filepath.Join(dir, "service.toml")}
+		service.Main.ID = "gql"
+		service.Main.ServiceURL = srv.URL
+		service.Main.Headers = [][]string{{"Content-Type", "text/plain"}}
+		service.Endpoint = []model.Endpoint{{
+			ID:        "user",
+			Type:      GraphQLEndpoint,
+			URI:       "/graphql",
+			QueryFile: "user.graphql",
+			Variables: map[string]interface{}{"id": "{$id}"},
+		}}
+
+		caller := NewCaller(NewHTTPClient())
+		pkg.Expect(t, nil, caller.LoadBodyFile("gql - user", service))
+
+		fields := caller.GetFields("gql - user", service)
+		pkg.Expect(t, true, fields["id"].Prompt != "")
+
+		fields["id"] = Field{Value: "abc"}
+
+		response, err := caller.Call("gql - user", service, fields)
+		pkg.Expect(t, nil, err)
+
+		body, err := caller.HTTPClient.ToString(response)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, `{"data":{"user":{"id":"1"}}}`, body)
+		pkg.Expect(t, http.MethodPost, method)
+		pkg.Expect(t, "application/json", contentType)
+		pkg.Expect(t, "query User($id: ID!) { user(id: $id) { id } }", request.Query)
+		pkg.Expect(t, "abc", request.Variables["id"])
+
+		service.Endpoint[0].Type = "soap"
+		_, err = caller.Call("gql - user", service, fields)
+		pkg.Expect(t, true, err != nil)
+	})
+
+	t.Run("TestIntrospection", func(t *testing.T) {
+		schema, err := ParseGraphQLSchema([]byte(introspectionResponse))
+		pkg.Expect(t, nil, err)
+
+		endpoints := schema.Endpoints("/graphql")
+		pkg.Expect(t, 3, len(endpoints))
+
+		pkg.Expect(t, "user", endpoints[0].ID)
+		pkg.Expect(t, "Query user", endpoints[0].Name)
+		pkg.Expect(t, "query user($id: ID!) {\n  user(id: $id) {\n    id\n    name\n  }\n}\n", endpoints[0].Query)
+		pkg.Expect(t, map[string]interface{}{"id": "{$id}"}, endpoints[0].Variables)
+
+		pkg.Expect(t, "query version {\n  version\n}\n", endpoints[1].Query)
+
+		pkg.Expect(t, "Mutation createUser", endpoints[2].Name)
+		pkg.Expect(t, true, strings.HasPrefix(endpoints[2].Query, "mutation createUser($name: String!, $tags: [String]) {"))
+		pkg.Expect(t, "{$tags:null}", endpoints[2].Variables["tags"])
+
+		stubs, err := RenderEndpoints(endpoints)
+		pkg.Expect(t, nil, err)
+
+		service := model.Service{}
+		_, err = toml.Decode(stubs, &service)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 3, len(service.Endpoint))
+		pkg.Expect(t, endpoints[0].Query, service.Endpoint[0].Query)
+		pkg.Expect(t, GraphQLEndpoint, service.Endpoint[2].Type)
+
+		_, err = ParseGraphQLSchema([]byte(`{"errors":[{"message":"introspection disabled"}]}`))
+		pkg.Expect(t, "Introspection failed: introspection disabled", err.Error())
+	})
+}
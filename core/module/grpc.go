@@ -0,0 +1,514 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/clivern/poodle/core/util"
+	. "github.com/logrusorgru/aurora/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// GRPCService is the type of grpc services
+const GRPCService = "grpc"
+
+// GRPCClient struct
+type GRPCClient struct {
+	// Descriptors are .proto or descriptor set files, server reflection is used if empty
+	Descriptors []string
+	// ImportPaths are the paths to search for imports of .proto files
+	ImportPaths []string
+	// ConnectTimeout is the timeout of establishing the connection if set
+	ConnectTimeout time.Duration
+}
+
+// GRPCResult struct
+type GRPCResult struct {
+	Method   string
+	Header   metadata.MD
+	Trailer  metadata.MD
+	Status   *status.Status
+	Messages int
+}
+
+// NewGRPCClient creates an instance of grpc client
+func NewGRPCClient() *GRPCClient {
+	return &GRPCClient{}
+}
+
+// Dial connects to a grpc server
+//
+// Targets with grpc:// or http:// are dialed in plaintext while grpcs://
+// and https:// use TLS.
+func (g *GRPCClient) Dial(ctx context.Context, target string) (*grpc.ClientConn, error) {
+	u, err := url.Parse(target)
+
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("Invalid grpc target %s, use grpc://host:port or grpcs://host:port", target)
+	}
+
+	creds := insecure.NewCredentials()
+
+	switch u.Scheme {
+	case "grpc", "http":
+	case "grpcs", "https":
+		creds = credentials.NewTLS(&tls.Config{})
+	default:
+		return nil, fmt.Errorf("Invalid grpc target scheme %s, use grpc or grpcs", u.Scheme)
+	}
+
+	host := u.Host
+
+	if u.Port() == "" {
+		port := "80"
+
+		if u.Scheme == "grpcs" || u.Scheme == "https" {
+			port = "443"
+		}
+
+		host = fmt.Sprintf("%s:%s", host, port)
+	}
+
+	if g.ConnectTimeout <= 0 {
+		return grpc.DialContext(ctx, host, grpc.WithTransportCredentials(creds))
+	}
+
+	// Connections are established lazily unless the dial blocks until they are ready
+	dialCtx, cancel := context.WithTimeout(ctx, g.ConnectTimeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(dialCtx, host, grpc.WithTransportCredentials(creds), grpc.WithBlock())
+
+	if err != nil && ctx.Err() == nil && dialCtx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("Timeout connecting to %s after %s", host, g.ConnectTimeout)
+	}
+
+	return conn, err
+}
+
+// FindMethod finds a method by its full name like package.Service/Method or package.Service.Method
+func (g *GRPCClient) FindMethod(ctx context.Context, conn *grpc.ClientConn, name string) (protoreflect.MethodDescriptor, error) {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "/")
+	i := strings.LastIndexAny(name, "/.")
+
+	if i <= 0 || i == len(name)-1 {
+		return nil, fmt.Errorf("Invalid grpc method %s, use package.Service/Method", name)
+	}
+
+	serviceName, methodName := name[:i], name[i+1:]
+
+	var files *protoregistry.Files
+	var err error
+
+	if len(g.Descriptors) > 0 {
+		files, err = g.LoadDescriptors()
+	} else {
+		files, err = g.Reflect(ctx, conn, serviceName)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to find grpc service %s", serviceName)
+	}
+
+	service, ok := desc.(protoreflect.ServiceDescriptor)
+
+	if !ok {
+		return nil, fmt.Errorf("%s is not a grpc service", serviceName)
+	}
+
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+
+	if method == nil {
+		return nil, fmt.Errorf("Unable to find method %s in grpc service %s", methodName, serviceName)
+	}
+
+	return method, nil
+}
+
+// Invoke calls a unary or server streaming method with a JSON request
+//
+// The handler gets each response as JSON, it stops after max responses if max is positive.
+func (g *GRPCClient) Invoke(ctx context.Context, conn *grpc.ClientConn, method protoreflect.MethodDescriptor, request string, headers map[string]string, max int, handler func(string) error) (*GRPCResult, error) {
+	if method.IsStreamingClient() {
+		return nil, fmt.Errorf("Client streaming method %s is not supported", method.FullName())
+	}
+
+	in := dynamicpb.NewMessage(method.Input())
+
+	if strings.TrimSpace(request) == "" {
+		request = "{}"
+	}
+
+	if err := protojson.Unmarshal([]byte(request), in); err != nil {
+		return nil, fmt.Errorf("Invalid request for %s: %s", method.FullName(), err.Error())
+	}
+
+	md := metadata.MD{}
+
+	for k, v := range headers {
+		// Connection specific headers are not allowed in grpc metadata
+		if strings.EqualFold(k, "Content-Type") || strings.EqualFold(k, "Connection") {
+			continue
+		}
+
+		md.Append(strings.ToLower(k), v)
+	}
+
+	// Cancel the call if it stops before the server ends the stream
+	ctx, cancel := context.WithCancel(metadata.NewOutgoingContext(ctx, md))
+	defer cancel()
+
+	result := &GRPCResult{
+		Method: fmt.Sprintf("/%s/%s", method.Parent().FullName(), method.Name()),
+	}
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: method.IsStreamingServer()}, result.Method)
+
+	if err != nil {
+		result.Status = status.Convert(err)
+		return result, nil
+	}
+
+	if err := stream.SendMsg(in); err != nil && err != io.EOF {
+		result.Status = status.Convert(err)
+		return result, nil
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	for {
+		out := dynamicpb.NewMessage(method.Output())
+		err := stream.RecvMsg(out)
+
+		if result.Header == nil {
+			result.Header, _ = stream.Header()
+		}
+
+		if err == io.EOF {
+			result.Trailer = stream.Trailer()
+			break
+		}
+
+		if err != nil {
+			result.Status = status.Convert(err)
+			result.Trailer = stream.Trailer()
+			return result, nil
+		}
+
+		data, err := protojson.MarshalOptions{Multiline: true, Indent: "    "}.Marshal(out)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result.Messages++
+
+		if err := handler(string(data)); err != nil {
+			return nil, err
+		}
+
+		if max > 0 && result.Messages >= max {
+			break
+		}
+	}
+
+	result.Status = status.New(codes.OK, "")
+
+	return result, nil
+}
+
+// LoadDescriptors loads descriptor set files and .proto files
+//
+// Descriptor sets are built with protoc --include_imports --descriptor_set_out
+// and .proto files are compiled into one with protoc.
+func (g *GRPCClient) LoadDescriptors() (*protoregistry.Files, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]bool{}
+
+	for _, path := range g.Descriptors {
+		var data []byte
+		var err error
+
+		if strings.HasSuffix(path, ".proto") {
+			data, err = g.compile(path)
+		} else {
+			data, err = ioutil.ReadFile(path)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		item := &descriptorpb.FileDescriptorSet{}
+
+		if err := proto.Unmarshal(data, item); err != nil {
+			return nil, fmt.Errorf("Invalid descriptor set %s: %s", path, err.Error())
+		}
+
+		for _, file := range item.File {
+			if !seen[file.GetName()] {
+				seen[file.GetName()] = true
+				set.File = append(set.File, file)
+			}
+		}
+	}
+
+	return buildFiles(set.File)
+}
+
+// Reflect fetches the descriptors of a service with server reflection
+func (g *GRPCClient) Reflect(ctx context.Context, conn *grpc.ClientConn, service string) (*protoregistry.Files, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("Server reflection failed: %s", err.Error())
+	}
+
+	defer stream.CloseSend()
+
+	files := map[string]*descriptorpb.FileDescriptorProto{}
+
+	fetch := func(req *reflectionpb.ServerReflectionRequest) error {
+		if err := stream.Send(req); err != nil {
+			return fmt.Errorf("Server reflection failed: %s", err.Error())
+		}
+
+		resp, err := stream.Recv()
+
+		if err != nil {
+			return fmt.Errorf("Server reflection failed: %s", err.Error())
+		}
+
+		if e := resp.GetErrorResponse(); e != nil {
+			return fmt.Errorf("Server reflection failed: %s", e.GetErrorMessage())
+		}
+
+		for _, data := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			file := &descriptorpb.FileDescriptorProto{}
+
+			if err := proto.Unmarshal(data, file); err != nil {
+				return fmt.Errorf("Server reflection failed: %s", err.Error())
+			}
+
+			files[file.GetName()] = file
+		}
+
+		return nil
+	}
+
+	err = fetch(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: service,
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch the dependencies the server didn't send, well known types are resolved locally
+	for missing := missingFiles(files); len(missing) > 0; missing = missingFiles(files) {
+		for _, name := range missing {
+			err = fetch(&reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{
+					FileByFilename: name,
+				},
+			})
+
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := files[name]; !ok {
+				return nil, fmt.Errorf("Server reflection failed: missing file %s", name)
+			}
+		}
+	}
+
+	items := []*descriptorpb.FileDescriptorProto{}
+
+	for _, file := range files {
+		items = append(items, file)
+	}
+
+	return buildFiles(items)
+}
+
+// compile compiles a .proto file into a descriptor set with protoc
+func (g *GRPCClient) compile(path string) ([]byte, error) {
+	if _, err := exec.LookPath("protoc"); err != nil {
+		return nil, fmt.Errorf("protoc is required to load %s, install it or use a descriptor set file", path)
+	}
+
+	out, err := ioutil.TempFile("", "poodle-*.protoset")
+
+	if err != nil {
+		return nil, err
+	}
+
+	out.Close()
+	defer os.Remove(out.Name())
+
+	args := []string{"--include_imports", fmt.Sprintf("--descriptor_set_out=%s", out.Name())}
+
+	for _, item := range append(g.ImportPaths, filepath.Dir(path)) {
+		args = append(args, fmt.Sprintf("--proto_path=%s", item))
+	}
+
+	args = append(args, path)
+
+	if output, err := exec.Command("protoc", args...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("Unable to compile %s: %s", path, strings.TrimSpace(string(output)))
+	}
+
+	return ioutil.ReadFile(out.Name())
+}
+
+// Pretty returns colored status and metadata of the call
+func (r *GRPCResult) Pretty() string {
+	value := "\n---\n"
+
+	value = value + fmt.Sprintf(
+		"%s %s %s\n",
+		Blue("gRPC"),
+		Blue(r.Status.Code()),
+		Cyan(r.Method),
+	)
+
+	if r.Status.Message() != "" {
+		value = value + fmt.Sprintf("%s\n", Red(r.Status.Message()))
+	}
+
+	for _, md := range []metadata.MD{r.Header, r.Trailer} {
+		keys := []string{}
+
+		for k := range md {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			for _, v := range md[k] {
+				value = value + fmt.Sprintf("%s: %s\n", Cyan(k), v)
+			}
+		}
+	}
+
+	return value
+}
+
+// missingFiles gets the dependencies that are neither fetched nor well known
+func missingFiles(files map[string]*descriptorpb.FileDescriptorProto) []string {
+	missing := []string{}
+
+	for _, file := range files {
+		for _, dep := range file.GetDependency() {
+			if _, ok := files[dep]; ok {
+				continue
+			}
+
+			if _, err := protoregistry.GlobalFiles.FindFileByPath(dep); err == nil {
+				continue
+			}
+
+			if !util.InArray(dep, missing) {
+				missing = append(missing, dep)
+			}
+		}
+	}
+
+	return missing
+}
+
+// buildFiles builds a registry from file descriptors in any order
+func buildFiles(items []*descriptorpb.FileDescriptorProto) (*protoregistry.Files, error) {
+	byName := map[string]*descriptorpb.FileDescriptorProto{}
+
+	for _, item := range items {
+		byName[item.GetName()] = item
+	}
+
+	files := &protoregistry.Files{}
+
+	var build func(name string) error
+
+	build = func(name string) error {
+		if _, err := files.FindFileByPath(name); err == nil {
+			return nil
+		}
+
+		file, ok := byName[name]
+
+		if !ok {
+			// Well known types are compiled in
+			fd, err := protoregistry.GlobalFiles.FindFileByPath(name)
+
+			if err != nil {
+				return fmt.Errorf("Unable to find proto file %s", name)
+			}
+
+			return files.RegisterFile(fd)
+		}
+
+		for _, dep := range file.GetDependency() {
+			if err := build(dep); err != nil {
+				return err
+			}
+		}
+
+		fd, err := protodesc.NewFile(file, files)
+
+		if err != nil {
+			return fmt.Errorf("Invalid proto file %s: %s", name, err.Error())
+		}
+
+		return files.RegisterFile(fd)
+	}
+
+	for _, item := range items {
+		if err := build(item.GetName()); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
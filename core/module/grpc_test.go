@@ -0,0 +1,194 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/pkg"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// grpcService gets a grpc service definition for the echo server
+func grpcService(target string) *model.Service {
+	service := &model.Service{}
+	service.Main.ID = "echo"
+	service.Main.Type = GRPCService
+	service.Main.ServiceURL = target
+	service.Main.Timeout = "5s"
+	service.Security.Scheme = "bearer"
+	service.Security.Bearer.Header = []string{"Authorization", "Bearer {$token}"}
+	service.Endpoint = []model.Endpoint{
+		{
+			ID:     "say",
+			Method: "poodle.test.Echo/Say",
+			Body:   `{"message": "{$message}"}`,
+		},
+		{
+			ID:     "repeat",
+			Method: "poodle.test.Echo.Repeat",
+			Body:   `{"message": "hello", "count": {$count:3}}`,
+		},
+		{
+			ID:     "missing",
+			Method: "poodle.test.Echo/Missing",
+		},
+	}
+
+	return service
+}
+
+// TestGRPC test cases
+func TestGRPC(t *testing.T) {
+	server, target, err := pkg.GRPCServerMock()
+	pkg.Expect(t, nil, err)
+	defer server.Stop()
+
+	t.Run("TestCallGRPCUnary", func(t *testing.T) {
+		service := grpcService(target)
+		caller := NewCaller(NewHTTPClient())
+
+		fields := caller.GetFields("echo - say", service)
+		pkg.Expect(t, 2, len(fields))
+
+		fields["token"] = Field{Value: "secret"}
+		fields["message"] = Field{Value: "hi"}
+
+		_, err := caller.Call("echo - say", service, fields)
+		pkg.Expect(t, "Service echo is a grpc service", err.Error())
+
+		messages := []string{}
+
+		result, err := caller.CallGRPC(context.TODO(), "echo - say", service, fields, 0, func(message string) error {
+			messages = append(messages, message)
+			return nil
+		})
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, codes.OK, result.Status.Code())
+		pkg.Expect(t, "/poodle.test.Echo/Say", result.Method)
+		pkg.Expect(t, []string{"say"}, result.Header.Get("x-echo"))
+		pkg.Expect(t, 1, len(messages))
+
+		// protojson output is unstable on purpose, compare the decoded message
+		reply := map[string]interface{}{}
+		pkg.Expect(t, nil, json.Unmarshal([]byte(messages[0]), &reply))
+		pkg.Expect(t, map[string]interface{}{"message": "hi", "token": "Bearer secret"}, reply)
+	})
+
+	t.Run("TestCallGRPCServerStreaming", func(t *testing.T) {
+		service := grpcService(target)
+		service.Endpoint[1].Public = true
+		caller := NewCaller(NewHTTPClient())
+
+		fields := caller.GetFields("echo - repeat", service)
+		pkg.Expect(t, 1, len(fields))
+		pkg.Expect(t, "3", fields["count"].Default)
+
+		fields["count"] = Field{IsOptional: true, Default: "3", Value: "3"}
+		count := 0
+
+		result, err := caller.CallGRPC(context.TODO(), "echo - repeat", service, fields, 0, func(message string) error {
+			count++
+			return nil
+		})
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, codes.OK, result.Status.Code())
+		pkg.Expect(t, 3, count)
+		pkg.Expect(t, 3, result.Messages)
+
+		fields["count"] = Field{IsOptional: true, Default: "3", Value: "10"}
+
+		result, err = caller.CallGRPC(context.TODO(), "echo - repeat", service, fields, 4, func(message string) error {
+			return nil
+		})
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 4, result.Messages)
+	})
+
+	t.Run("TestCallGRPCDescriptorSet", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		data, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+			File: []*descriptorpb.FileDescriptorProto{pkg.EchoFileDescriptor()},
+		})
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, nil, ioutil.WriteFile(filepath.Join(dir, "echo.protoset"), data, 0600))
+
+		service := grpcService(target)
+		service.Path = filepath.Join(dir, "echo.toml")
+		service.Main.Descriptors = []string{"echo.protoset"}
+		service.Endpoint[0].Public = true
+
+		caller := NewCaller(NewHTTPClient())
+
+		result, err := caller.CallGRPC(context.TODO(), "echo - say", service, map[string]Field{
+			"message": {Value: "from descriptor"},
+		}, 0, func(message string) error {
+			pkg.Expect(t, true, strings.Contains(message, "from descriptor"))
+			return nil
+		})
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 1, result.Messages)
+	})
+
+	t.Run("TestCallGRPCErrors", func(t *testing.T) {
+		service := grpcService(target)
+		service.Endpoint[0].Public = true
+		service.Endpoint[2].Public = true
+
+		caller := NewCaller(NewHTTPClient())
+		handler := func(message string) error { return nil }
+
+		_, err := caller.CallGRPC(context.TODO(), "echo - missing", service, map[string]Field{}, 0, handler)
+		pkg.Expect(t, "Unable to find method Missing in grpc service poodle.test.Echo", err.Error())
+
+		service.Endpoint[0].Body = `{"unknown": 1}`
+
+		_, err = caller.CallGRPC(context.TODO(), "echo - say", service, map[string]Field{}, 0, handler)
+		pkg.Expect(t, true, strings.HasPrefix(err.Error(), "Invalid request for poodle.test.Echo.Say"))
+
+		service.Endpoint[0].Method = "poodle.test.Unknown/Say"
+
+		_, err = caller.CallGRPC(context.TODO(), "echo - say", service, map[string]Field{}, 0, handler)
+		pkg.Expect(t, true, strings.HasPrefix(err.Error(), "Server reflection failed"))
+
+		// Servers that never complete the handshake are bounded by the connect timeout
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		pkg.Expect(t, nil, err)
+		defer listener.Close()
+
+		service.Endpoint[0].Method = "poodle.test.Echo/Say"
+		service.Main.ServiceURL = fmt.Sprintf("grpc://%s", listener.Addr().String())
+		service.Main.ConnectTimeout = "100ms"
+
+		start := time.Now()
+		_, err = caller.CallGRPC(context.TODO(), "echo - say", service, map[string]Field{}, 0, handler)
+		pkg.Expect(t, fmt.Sprintf("Timeout connecting to %s after 100ms", listener.Addr().String()), err.Error())
+		pkg.Expect(t, true, time.Since(start) < time.Second)
+
+		service.Main.ServiceURL = "ftp://127.0.0.1:1"
+
+		_, err = caller.CallGRPC(context.TODO(), "echo - say", service, map[string]Field{}, 0, handler)
+		pkg.Expect(t, "Invalid grpc target scheme ftp, use grpc or grpcs", err.Error())
+	})
+}
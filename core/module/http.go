@@ -8,173 +8,339 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"time"
+
+	"github.com/clivern/poodle/core/util"
 )
 
+// MaxDrainBody is the max body size read to reuse a connection
+const MaxDrainBody = 1 << 16
+
+// UnixSocketHost is the host used for requests sent over a unix socket
+const UnixSocketHost = "unix"
+
+// methodChars are the allowed characters in a http method (RFC 7230 token)
+const methodChars = "!#$%&'*+-.^_`|~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// StandardMethods are the http methods accepted in any case
+var StandardMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
 // HTTPClient struct
 type HTTPClient struct {
+	// Timeout is the whole request timeout including reading the body
 	Timeout time.Duration
+	// ConnectTimeout is the timeout of establishing a connection if set
+	ConnectTimeout time.Duration
+	// ReadTimeout is the timeout of waiting for the response headers if set
+	ReadTimeout time.Duration
+	// Dumper dumps the requests and responses on the wire if set
+	Dumper *Dumper
+	// Retry retries failed requests if set
+	Retry *RetryPolicy
+	// OnRetry gets called before retrying a failed attempt
+	OnRetry func(attempt RetryAttempt)
+	// Jar stores and sends cookies if set
+	Jar http.CookieJar
+	// Redirect decides which redirects to follow, it follows up to 10 redirects if not set
+	Redirect *RedirectPolicy
+	// Protocol is the http protocol to speak, it is ignored if a transport is set
+	Protocol string
+	// Transport sends the requests, the shared transport of the protocol is used if not set
+	Transport http.RoundTripper
+	// Middlewares wrap the transport, the first one is the outermost
+	Middlewares []Middleware
+	// Redactor redacts the secret values from the output and logs
+	Redactor *Redactor
 }
 
 // NewHTTPClient creates an instance of http client
 func NewHTTPClient() *HTTPClient {
 	return &HTTPClient{
-		Timeout: time.Duration(30),
+		Timeout:  30 * time.Second,
+		Redactor: NewRedactor(),
 	}
 }
 
+// Use appends middlewares to the client's middleware chain
+func (h *HTTPClient) Use(middlewares ...Middleware) {
+	chain := make([]Middleware, 0, len(h.Middlewares)+len(middlewares))
+	chain = append(chain, h.Middlewares...)
+	h.Middlewares = append(chain, middlewares...)
+}
+
 // Get http call
 func (h *HTTPClient) Get(ctx context.Context, endpoint string, parameters, headers map[string]string) (*http.Response, error) {
+	return h.Request(ctx, http.MethodGet, endpoint, "", parameters, headers)
+}
 
-	endpoint, err := h.BuildParameters(endpoint, parameters)
-
-	if err != nil {
-		return nil, err
-	}
-
-	req, _ := http.NewRequest("GET", endpoint, nil)
+// Post http call
+func (h *HTTPClient) Post(ctx context.Context, endpoint string, data string, parameters, headers map[string]string) (*http.Response, error) {
+	return h.Request(ctx, http.MethodPost, endpoint, data, parameters, headers)
+}
 
-	req = req.WithContext(ctx)
+// Put http call
+func (h *HTTPClient) Put(ctx context.Context, endpoint string, data string, parameters, headers map[string]string) (*http.Response, error) {
+	return h.Request(ctx, http.MethodPut, endpoint, data, parameters, headers)
+}
 
-	for k, v := range headers {
-		req.Header.Add(k, v)
-	}
+// Patch http call
+func (h *HTTPClient) Patch(ctx context.Context, endpoint string, data string, parameters, headers map[string]string) (*http.Response, error) {
+	return h.Request(ctx, http.MethodPatch, endpoint, data, parameters, headers)
+}
 
-	client := http.Client{
-		Timeout: time.Second * h.Timeout,
-	}
+// Delete http call
+func (h *HTTPClient) Delete(ctx context.Context, endpoint string, parameters, headers map[string]string) (*http.Response, error) {
+	return h.Request(ctx, http.MethodDelete, endpoint, "", parameters, headers)
+}
 
-	resp, err := client.Do(req)
+// Request http call with any method, the body is sent if not empty
+func (h *HTTPClient) Request(ctx context.Context, method, endpoint, data string, parameters, headers map[string]string) (*http.Response, error) {
+	var body io.Reader = http.NoBody
 
-	if err != nil {
-		return resp, err
+	if data != "" {
+		body = bytes.NewBufferString(data)
 	}
 
-	return resp, err
+	return h.RequestWithBody(ctx, method, endpoint, body, parameters, headers)
 }
 
-// Post http call
-func (h *HTTPClient) Post(ctx context.Context, endpoint string, data string, parameters, headers map[string]string) (*http.Response, error) {
+// RequestWithBody http call with any method and a streamed body
+func (h *HTTPClient) RequestWithBody(ctx context.Context, method, endpoint string, body io.Reader, parameters, headers map[string]string) (*http.Response, error) {
+	client, err := h.newClient()
+
+	var req *http.Request
 
-	endpoint, err := h.BuildParameters(endpoint, parameters)
+	if err == nil {
+		req, err = h.newRequest(ctx, method, endpoint, body, parameters, headers)
+	}
 
 	if err != nil {
+		// Release files and pipes since the body won't be consumed
+		if closer, ok := body.(io.Closer); ok {
+			closer.Close()
+		}
 		return nil, err
 	}
 
-	req, _ := http.NewRequest("POST", endpoint, bytes.NewBufferString(data))
+	ctx = req.Context()
 
-	req = req.WithContext(ctx)
-
-	for k, v := range headers {
-		req.Header.Add(k, v)
+	if h.ConnectTimeout > 0 {
+		ctx = context.WithValue(ctx, connectTimeoutKey{}, h.ConnectTimeout)
 	}
 
-	client := http.Client{
-		Timeout: time.Second * h.Timeout,
-	}
+	for attempt := 1; ; attempt++ {
+		timing := NewTiming()
+		req = req.WithContext(WithRedirects(WithTiming(ctx, timing), &RedirectChain{}))
 
-	resp, err := client.Do(req)
+		timing.Begin()
 
-	if err != nil {
-		return resp, err
-	}
+		resp, err := client.Do(req)
 
-	return resp, err
-}
+		wait, retry := h.Retry.ShouldRetry(req, attempt, resp, err)
 
-// Put http call
-func (h *HTTPClient) Put(ctx context.Context, endpoint string, data string, parameters, headers map[string]string) (*http.Response, error) {
+		// Streamed bodies can't be sent twice
+		if retry && req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			retry = false
+		}
 
-	endpoint, err := h.BuildParameters(endpoint, parameters)
+		if !retry {
+			if err != nil {
+				return resp, err
+			}
 
-	if err != nil {
-		return nil, err
-	}
+			resp.Body = &timedBody{ReadCloser: resp.Body, timing: timing}
 
-	req, _ := http.NewRequest("PUT", endpoint, bytes.NewBufferString(data))
+			return resp, nil
+		}
 
-	req = req.WithContext(ctx)
+		item := RetryAttempt{Number: attempt, Err: err, Wait: wait}
 
-	for k, v := range headers {
-		req.Header.Add(k, v)
+		if resp != nil {
+			item.StatusCode = resp.StatusCode
+			h.DrainAndClose(resp)
+		}
+
+		if h.OnRetry != nil {
+			h.OnRetry(item)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+
+			if err != nil {
+				return nil, err
+			}
+
+			req.Body = body
+		}
 	}
+}
 
-	client := http.Client{
-		Timeout: time.Second * h.Timeout,
+// newRequest builds a http request, requests to unix sockets carry the socket in the URL host
+func (h *HTTPClient) newRequest(ctx context.Context, method, endpoint string, body io.Reader, parameters, headers map[string]string) (*http.Request, error) {
+	method, err := h.NormalizeMethod(method)
+
+	if err != nil {
+		return nil, err
 	}
 
-	resp, err := client.Do(req)
+	socket, endpoint, err := h.ResolveSocket(endpoint)
 
 	if err != nil {
-		return resp, err
+		return nil, err
 	}
 
-	return resp, err
-}
+	endpoint, err = h.BuildParameters(endpoint, parameters)
 
-// Patch http call
-func (h *HTTPClient) Patch(ctx context.Context, endpoint string, data string, parameters, headers map[string]string) (*http.Response, error) {
+	if err != nil {
+		return nil, err
+	}
 
-	endpoint, err := h.BuildParameters(endpoint, parameters)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
 
 	if err != nil {
 		return nil, err
 	}
 
-	req, _ := http.NewRequest("PATCH", endpoint, bytes.NewBufferString(data))
+	if socket != "" {
+		req.URL.Host = EncodeSocketHost(socket)
+		req.Host = UnixSocketHost
+	}
 
-	req = req.WithContext(ctx)
+	// Send files with a known length instead of chunked encoding
+	// and reopen them if the request is retried
+	if file, ok := body.(*os.File); ok {
+		if fi, err := file.Stat(); err == nil {
+			req.ContentLength = fi.Size()
+		}
+
+		req.GetBody = func() (io.ReadCloser, error) {
+			return os.Open(file.Name())
+		}
+	}
 
 	for k, v := range headers {
 		req.Header.Add(k, v)
 	}
 
-	client := http.Client{
-		Timeout: time.Second * h.Timeout,
+	return req, nil
+}
+
+// NormalizeMethod validates a http method and converts it to upper case
+//
+// Standard methods are accepted in any case (get, Get, GET). Custom verbs
+// like PROPFIND or PURGE must be either all upper or all lower case since
+// servers treat methods as case-sensitive.
+func (h *HTTPClient) NormalizeMethod(method string) (string, error) {
+	method = strings.TrimSpace(method)
+
+	if method == "" {
+		return "", fmt.Errorf("HTTP method must not be empty")
+	}
+
+	for _, c := range method {
+		if !strings.ContainsRune(methodChars, c) {
+			return "", fmt.Errorf("Invalid character %q in HTTP method %s", c, method)
+		}
 	}
 
-	resp, err := client.Do(req)
+	upper := strings.ToUpper(method)
 
-	if err != nil {
-		return resp, err
+	if util.InArray(upper, StandardMethods) {
+		return upper, nil
+	}
+
+	if method != upper && method != strings.ToLower(method) {
+		return "", fmt.Errorf("Ambiguous casing for custom HTTP method %s, use %s", method, upper)
 	}
 
-	return resp, err
+	return upper, nil
 }
 
-// Delete http call
-func (h *HTTPClient) Delete(ctx context.Context, endpoint string, parameters, headers map[string]string) (*http.Response, error) {
+// ResolveSocket splits a unix socket URL into the socket path and a plain http URL
+//
+// Both unix:///var/run/docker.sock/v1.41/info and http+unix:///var/run/docker.sock/v1.41/info
+// are supported, in that case the socket is the longest existing socket file in the path.
+// The socket path can also be percent-encoded as the host like http+unix://%2Fvar%2Frun%2Fdocker.sock/v1.41/info
+func (h *HTTPClient) ResolveSocket(endpoint string) (string, string, error) {
+	var rest string
+
+	switch {
+	case strings.HasPrefix(endpoint, "unix://"):
+		rest = strings.TrimPrefix(endpoint, "unix://")
+	case strings.HasPrefix(endpoint, "http+unix://"):
+		rest = strings.TrimPrefix(endpoint, "http+unix://")
+	default:
+		return "", endpoint, nil
+	}
 
-	endpoint, err := h.BuildParameters(endpoint, parameters)
+	path := rest
+	suffix := ""
 
-	if err != nil {
-		return nil, err
+	if i := strings.IndexAny(rest, "?#"); i >= 0 {
+		path = rest[:i]
+		suffix = rest[i:]
 	}
 
-	req, _ := http.NewRequest("DELETE", endpoint, nil)
+	// Incase the socket path is percent-encoded as the host
+	if !strings.HasPrefix(path, "/") {
+		host := path
+		path = "/"
 
-	req = req.WithContext(ctx)
+		if i := strings.Index(host, "/"); i >= 0 {
+			path = host[i:]
+			host = host[:i]
+		}
 
-	for k, v := range headers {
-		req.Header.Add(k, v)
-	}
+		socket, err := url.PathUnescape(host)
+
+		if err != nil {
+			return "", "", err
+		}
 
-	client := http.Client{
-		Timeout: time.Second * h.Timeout,
+		return socket, fmt.Sprintf("http://%s%s%s", UnixSocketHost, path, suffix), nil
 	}
 
-	resp, err := client.Do(req)
+	socket := ""
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
 
-	if err != nil {
-		return resp, err
+	for i := range segments {
+		candidate := "/" + strings.Join(segments[:i+1], "/")
+
+		if fi, err := os.Stat(candidate); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			socket = candidate
+			path = "/" + strings.Join(segments[i+1:], "/")
+			break
+		}
+	}
+
+	if socket == "" {
+		return "", "", fmt.Errorf("Unable to locate a unix socket in %s", endpoint)
 	}
 
-	return resp, err
+	return socket, fmt.Sprintf("http://%s%s%s", UnixSocketHost, path, suffix), nil
 }
 
 // BuildParameters add parameters to URL
@@ -206,6 +372,46 @@ func (h *HTTPClient) BuildData(parameters map[string]string) string {
 	return strings.Join(items, "&")
 }
 
+// newClient creates a http client on top of the transport and the middleware chain
+func (h *HTTPClient) newClient() (http.Client, error) {
+	transport := h.Transport
+
+	if transport == nil {
+		var err error
+
+		transport, err = ProtocolTransport(h.Protocol)
+
+		if err != nil {
+			return http.Client{}, err
+		}
+	}
+
+	middlewares := append([]Middleware{}, h.Middlewares...)
+	middlewares = append(middlewares, ReadTimeoutMiddleware(h.ReadTimeout))
+
+	// The dumper sits next to the wire to show the final requests
+	if h.Dumper != nil {
+		middlewares = append(middlewares, h.Dumper.Transport)
+	}
+
+	return http.Client{
+		Transport:     Chain(transport, middlewares...),
+		Timeout:       h.Timeout,
+		Jar:           h.Jar,
+		CheckRedirect: h.Redirect.CheckRedirect,
+	}, nil
+}
+
+// DrainAndClose reads the rest of the response body and closes it so the connection can be reused
+func (h *HTTPClient) DrainAndClose(response *http.Response) {
+	if response == nil || response.Body == nil {
+		return
+	}
+
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, MaxDrainBody))
+	response.Body.Close()
+}
+
 // ToString response body to string
 func (h *HTTPClient) ToString(response *http.Response) (string, error) {
 	defer response.Body.Close()
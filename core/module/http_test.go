@@ -6,7 +6,13 @@ package module
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -226,3 +232,162 @@ func TestBuildData(t *testing.T) {
 		pkg.Expect(t, httpClient.BuildData(map[string]string{"arg1": "value1"}), "arg1=value1")
 	})
 }
+
+// TestResolveSocket test cases
+func TestResolveSocket(t *testing.T) {
+	t.Run("TestResolveSocket", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		socket := filepath.Join(dir, "api.sock")
+		srv, err := pkg.SocketServerMock(socket, "/v1/info", "ok", http.StatusOK)
+		pkg.Expect(t, nil, err)
+		defer srv.Close()
+
+		httpClient := NewHTTPClient()
+
+		path, endpoint, err := httpClient.ResolveSocket("https://example.com/v1/info")
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "", path)
+		pkg.Expect(t, "https://example.com/v1/info", endpoint)
+
+		path, endpoint, err = httpClient.ResolveSocket(fmt.Sprintf("unix://%s/v1/info?arg1=value1", socket))
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, socket, path)
+		pkg.Expect(t, "http://unix/v1/info?arg1=value1", endpoint)
+
+		path, endpoint, err = httpClient.ResolveSocket(fmt.Sprintf("http+unix://%s/", socket))
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, socket, path)
+		pkg.Expect(t, "http://unix/", endpoint)
+
+		path, endpoint, err = httpClient.ResolveSocket(fmt.Sprintf("http+unix://%s/v1/info", url.PathEscape(socket)))
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, socket, path)
+		pkg.Expect(t, "http://unix/v1/info", endpoint)
+
+		_, _, err = httpClient.ResolveSocket(fmt.Sprintf("unix://%s/missing.sock/v1/info", dir))
+		pkg.Expect(t, true, err != nil)
+	})
+}
+
+// TestHttpGetUnixSocket test cases
+func TestHttpGetUnixSocket(t *testing.T) {
+	t.Run("TestHttpGetUnixSocket", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		socket := filepath.Join(dir, "api.sock")
+		srv, err := pkg.SocketServerMock(socket, "/v1/info", `{"status":"ok"}`, http.StatusOK)
+		pkg.Expect(t, nil, err)
+		defer srv.Close()
+
+		httpClient := NewHTTPClient()
+		response, err := httpClient.Get(
+			context.TODO(),
+			fmt.Sprintf("unix://%s/v1/info", socket),
+			map[string]string{"arg1": "value1"},
+			map[string]string{"X-Api-Key": "poodle-123"},
+		)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, http.StatusOK, httpClient.GetStatusCode(response))
+		pkg.Expect(t, "arg1=value1", httpClient.GetHeaderValue(response, "X-Request-Query"))
+
+		body, err := httpClient.ToString(response)
+
+		pkg.Expect(t, `{"status":"ok"}`, body)
+		pkg.Expect(t, nil, err)
+
+		response, err = httpClient.Post(
+			context.TODO(),
+			fmt.Sprintf("http+unix://%s/v1/info", url.PathEscape(socket)),
+			`{"name":"poodle"}`,
+			map[string]string{},
+			map[string]string{},
+		)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, http.StatusOK, httpClient.GetStatusCode(response))
+	})
+}
+
+// TestNormalizeMethod test cases
+func TestNormalizeMethod(t *testing.T) {
+	t.Run("TestNormalizeMethod", func(t *testing.T) {
+		httpClient := NewHTTPClient()
+
+		for input, want := range map[string]string{
+			"get":      "GET",
+			"Head":     "HEAD",
+			"OPTIONS":  "OPTIONS",
+			"trace":    "TRACE",
+			"PROPFIND": "PROPFIND",
+			"purge":    "PURGE",
+		} {
+			method, err := httpClient.NormalizeMethod(input)
+			pkg.Expect(t, nil, err)
+			pkg.Expect(t, want, method)
+		}
+
+		for _, input := range []string{"", "  ", "PropFind", "GE T", "GE\nT"} {
+			_, err := httpClient.NormalizeMethod(input)
+			pkg.Expect(t, true, err != nil)
+		}
+	})
+}
+
+// TestHttpRequest test cases
+func TestHttpRequest(t *testing.T) {
+	t.Run("TestHttpRequest", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Header().Set("X-Method", r.Method)
+			w.Header().Set("X-Body", string(body))
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		httpClient := NewHTTPClient()
+
+		for _, method := range []string{"head", "OPTIONS", "trace", "PROPFIND", "purge"} {
+			response, err := httpClient.Request(
+				context.TODO(),
+				method,
+				srv.URL,
+				"",
+				map[string]string{},
+				map[string]string{},
+			)
+
+			pkg.Expect(t, nil, err)
+			pkg.Expect(t, strings.ToUpper(method), httpClient.GetHeaderValue(response, "X-Method"))
+			pkg.Expect(t, "", httpClient.GetHeaderValue(response, "X-Body"))
+		}
+
+		response, err := httpClient.Request(
+			context.TODO(),
+			"get",
+			srv.URL,
+			`{"name":"poodle"}`,
+			map[string]string{},
+			map[string]string{},
+		)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, `{"name":"poodle"}`, httpClient.GetHeaderValue(response, "X-Body"))
+
+		_, err = httpClient.Request(
+			context.TODO(),
+			"PropFind",
+			srv.URL,
+			"",
+			map[string]string{},
+			map[string]string{},
+		)
+
+		pkg.Expect(t, true, err != nil)
+	})
+}
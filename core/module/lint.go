@@ -0,0 +1,703 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/core/util"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// ErrorSeverity is the severity of a mistake that breaks the definition
+	ErrorSeverity = "error"
+	// WarningSeverity is the severity of a suspicious definition
+	WarningSeverity = "warning"
+)
+
+var (
+	// SecuritySchemes are the supported security schemes
+	SecuritySchemes = []string{"none", "basic", "bearer", "api_key"}
+
+	// HTTPSchemes are the supported schemes of a service url
+	HTTPSchemes = []string{"http", "https", "ws", "wss", "unix", "http+unix"}
+
+	// GRPCSchemes are the supported schemes of a grpc service url
+	GRPCSchemes = []string{"grpc", "grpcs", "http", "https"}
+
+	// BodyTypes are the supported endpoint body types
+	BodyTypes = []string{RawBody, MultipartBody, URLEncodedBody, FileBody}
+)
+
+var (
+	// arrayHeader matches an array of tables header like [[Endpoint]]
+	arrayHeader = regexp.MustCompile(`^\s*\[\[\s*([A-Za-z0-9_.\-]+)\s*\]\]`)
+
+	// tableHeader matches a table header like [Main]
+	tableHeader = regexp.MustCompile(`^\s*\[\s*([A-Za-z0-9_.\-]+)\s*\]`)
+
+	// keyValue matches a key value pair like id = "value"
+	keyValue = regexp.MustCompile(`^\s*([A-Za-z0-9_\-]+)\s*=\s*(.*)$`)
+
+	// grpcMethod matches a grpc method like package.Service/Method
+	grpcMethod = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*[./][A-Za-z_][A-Za-z0-9_]*$`)
+
+	// parseErrorPrefix matches the position prefix of a toml parse error
+	parseErrorPrefix = regexp.MustCompile(`^toml: line \d+( \(last key "[^"]*"\))?: `)
+)
+
+// Diagnostic is a problem found in a service definition file
+type Diagnostic struct {
+	Path     string
+	Line     int
+	Column   int
+	Severity string
+	Message  string
+}
+
+// Linter checks service definition files
+type Linter struct {
+	Diagnostics []Diagnostic
+
+	// services are the locations of the checked services by id
+	services map[string]string
+}
+
+// lintFile is the state of a file being checked
+type lintFile struct {
+	linter *Linter
+	path   string
+	// lines are the lines of the tables and keys like Endpoint.0.method
+	lines map[string]int
+	// values are the positions of the string values
+	values map[string][2]int
+}
+
+// NewLinter creates an instance of linter
+func NewLinter() *Linter {
+	return &Linter{
+		Diagnostics: []Diagnostic{},
+		services:    make(map[string]string),
+	}
+}
+
+// String formats the diagnostic like path:line:column: severity: message
+func (d Diagnostic) String() string {
+	position := d.Path
+
+	if d.Line > 0 {
+		position = fmt.Sprintf("%s:%d", position, d.Line)
+	}
+
+	if d.Line > 0 && d.Column > 0 {
+		position = fmt.Sprintf("%s:%d", position, d.Column)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", position, d.Severity, d.Message)
+}
+
+// Errors counts the diagnostics with the error severity
+func (l *Linter) Errors() int {
+	count := 0
+
+	for _, item := range l.Diagnostics {
+		if item.Severity == ErrorSeverity {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Lint checks a service definition file
+//
+// A .poodle.toml file without endpoints is skipped since it may hold the
+// project config alone.
+func (l *Linter) Lint(path string) {
+	start := len(l.Diagnostics)
+
+	defer func() {
+		items := l.Diagnostics[start:]
+
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].Line != items[j].Line {
+				return items[i].Line < items[j].Line
+			}
+
+			return items[i].Message < items[j].Message
+		})
+	}()
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		l.Diagnostics = append(l.Diagnostics, Diagnostic{Path: path, Severity: ErrorSeverity, Message: err.Error()})
+		return
+	}
+
+	f := &lintFile{linter: l, path: path}
+	f.lines, f.values = indexLines(string(data))
+
+	service := model.NewEmptyService(filepath.Base(path))
+	service.Path = path
+	meta, err := toml.Decode(string(data), service)
+
+	if err != nil {
+		var perr toml.ParseError
+
+		if errors.As(err, &perr) {
+			line, column := position(string(data), perr.Position.Start)
+
+			l.Diagnostics = append(l.Diagnostics, Diagnostic{
+				Path:     path,
+				Line:     line,
+				Column:   column,
+				Severity: ErrorSeverity,
+				Message:  parseErrorPrefix.ReplaceAllString(perr.Error(), ""),
+			})
+			return
+		}
+
+		l.Diagnostics = append(l.Diagnostics, Diagnostic{Path: path, Severity: ErrorSeverity, Message: err.Error()})
+		return
+	}
+
+	if filepath.Base(path) == ProjectFile && len(service.Endpoint) == 0 {
+		return
+	}
+
+	for _, key := range meta.Undecoded() {
+		f.report(WarningSeverity, f.find(key.String()), "Unknown key %s", key.String())
+	}
+
+	f.lintMain(service)
+	f.lintSecurity(service)
+
+	if err := CheckVariables(service); err != nil {
+		f.report(ErrorSeverity, "Variables", "%s", err.Error())
+	}
+
+	ids := make(map[string]bool)
+	public := 0
+
+	for i, end := range service.Endpoint {
+		key := fmt.Sprintf("Endpoint.%d", i)
+
+		switch {
+		case end.ID == "":
+			f.report(ErrorSeverity, key, "Missing endpoint id")
+		case ids[end.ID]:
+			f.report(ErrorSeverity, key+".id", "Duplicate endpoint id %s", end.ID)
+		}
+
+		ids[end.ID] = true
+
+		if end.Public {
+			public++
+		}
+
+		f.lintEndpoint(service, key, end)
+	}
+
+	scheme := service.Security.Scheme
+
+	if public > 0 && public == len(service.Endpoint) && scheme != "" && scheme != "none" {
+		f.report(WarningSeverity, "Security.scheme", "Security scheme %s is never used since all endpoints are public", scheme)
+	}
+}
+
+// lintMain checks the service main settings
+func (f *lintFile) lintMain(service *model.Service) {
+	id := service.Main.ID
+	location := fmt.Sprintf("%s:%d", f.path, f.line("Main.id"))
+
+	if id == "" {
+		f.report(ErrorSeverity, "Main", "Missing service id")
+	} else if other, ok := f.linter.services[id]; ok {
+		f.report(ErrorSeverity, "Main.id", "Duplicate service id %s, also defined in %s", id, other)
+	} else {
+		f.linter.services[id] = location
+	}
+
+	grpc := strings.EqualFold(service.Main.Type, GRPCService)
+
+	if service.Main.Type != "" && !grpc {
+		f.report(ErrorSeverity, "Main.type", "Unknown service type %s, use %s or leave it empty", service.Main.Type, GRPCService)
+	}
+
+	if service.Main.Protocol != "" && !util.InArray(service.Main.Protocol, Protocols) {
+		f.report(ErrorSeverity, "Main.protocol", "Unknown protocol %s, use one of %s", service.Main.Protocol, strings.Join(Protocols, ", "))
+	}
+
+	f.template("Main.service_url", service.Main.ServiceURL)
+	f.lintURL(service)
+	f.pairs("Main.headers", "header", service.Main.Headers)
+	f.timeouts("Main", service.Main.Timeout, service.Main.ConnectTimeout, service.Main.ReadTimeout)
+	f.retry("Main.Retry", service.Main.Retry)
+}
+
+// lintURL checks the scheme of the service url, a url built from a variable
+// without default is skipped
+func (f *lintFile) lintURL(service *model.Service) {
+	tokens, err := Tokenize(service.Main.ServiceURL)
+
+	if err != nil || len(tokens) == 0 || (tokens[0].Kind != TextToken && !tokens[0].Optional) {
+		return
+	}
+
+	evaluator := &Evaluator{Fields: (&Caller{}).ParseFields(service.Main.ServiceURL)}
+	value, err := evaluator.Execute(tokens)
+
+	if err != nil {
+		return
+	}
+
+	schemes := HTTPSchemes
+
+	if strings.EqualFold(service.Main.Type, GRPCService) {
+		schemes = GRPCSchemes
+	}
+
+	u, err := url.Parse(value)
+
+	if err != nil || u.Scheme == "" {
+		f.report(ErrorSeverity, "Main.service_url", "Invalid service url %s, use a scheme like %s://", value, schemes[1])
+		return
+	}
+
+	if !util.InArray(strings.ToLower(u.Scheme), schemes) {
+		f.report(ErrorSeverity, "Main.service_url", "Unknown scheme %s of service url, use one of %s", u.Scheme, strings.Join(schemes, ", "))
+	}
+}
+
+// lintSecurity checks the security scheme and its headers
+func (f *lintFile) lintSecurity(service *model.Service) {
+	security := service.Security
+
+	if security.Scheme != "" && !util.InArray(security.Scheme, SecuritySchemes) {
+		f.report(ErrorSeverity, "Security.scheme", "Unknown security scheme %s, use one of %s", security.Scheme, strings.Join(SecuritySchemes, ", "))
+	}
+
+	for _, item := range []struct {
+		Scheme string
+		Key    string
+		Header []string
+	}{
+		{"api_key", "Security.ApiKey.header", security.APIKey.Header},
+		{"bearer", "Security.Bearer.header", security.Bearer.Header},
+		{"basic", "Security.Basic.header", security.Basic.Header},
+	} {
+		active := security.Scheme == item.Scheme
+
+		if len(item.Header) != 2 && (active || len(item.Header) > 0) {
+			f.report(ErrorSeverity, item.Key, "Invalid %s header %s, use [name, value]", item.Scheme, pairString(item.Header))
+			continue
+		}
+
+		if active && item.Scheme != "basic" {
+			f.template(item.Key, item.Header[1])
+		}
+	}
+
+	if security.Scheme == "basic" {
+		f.template("Security.Basic.username", security.Basic.Username)
+		f.template("Security.Basic.password", security.Basic.Password)
+	}
+}
+
+// lintEndpoint checks an endpoint
+func (f *lintFile) lintEndpoint(service *model.Service, key string, end model.Endpoint) {
+	websocket := strings.EqualFold(end.Type, WebSocketEndpoint)
+	graphql := strings.EqualFold(end.Type, GraphQLEndpoint)
+
+	if end.Type != "" && !websocket && !graphql {
+		f.report(ErrorSeverity, key+".type", "Unknown endpoint type %s, use %s or %s", end.Type, GraphQLEndpoint, WebSocketEndpoint)
+	}
+
+	switch {
+	case strings.EqualFold(service.Main.Type, GRPCService):
+		if !strings.Contains(end.Method, "{$") && !grpcMethod.MatchString(end.Method) {
+			f.report(ErrorSeverity, key+".method", "Invalid grpc method %s, use package.Service/Method", end.Method)
+		}
+	case websocket || (graphql && end.Method == ""):
+	default:
+		method, err := (&HTTPClient{}).NormalizeMethod(end.Method)
+
+		if err != nil {
+			f.report(ErrorSeverity, key+".method", "%s", err.Error())
+		} else if !util.InArray(method, StandardMethods) {
+			f.report(WarningSeverity, key+".method", "Unknown HTTP method %s, custom verbs are sent as is", method)
+		}
+	}
+
+	if end.BodyType != "" && !util.InArray(strings.ToLower(end.BodyType), BodyTypes) {
+		f.report(ErrorSeverity, key+".body_type", "Unknown body type %s, use one of %s", end.BodyType, strings.Join(BodyTypes, ", "))
+	}
+
+	if end.Redirect.Policy != "" && !util.InArray(strings.ToLower(end.Redirect.Policy), []string{"follow", "none"}) {
+		f.report(ErrorSeverity, key+".Redirect.policy", "Unknown redirect policy %s, use follow or none", end.Redirect.Policy)
+	}
+
+	if end.Public && (service.Security.Scheme == "" || service.Security.Scheme == "none") {
+		f.report(WarningSeverity, key+".public", "Endpoint is public but the service has no security scheme")
+	}
+
+	for _, file := range []struct {
+		Key  string
+		Path string
+	}{
+		{key + ".body_file", end.BodyFile},
+		{key + ".query_file", end.QueryFile},
+	} {
+		if file.Path != "" && !util.FileExists(relativePath(service, file.Path)) {
+			f.report(ErrorSeverity, file.Key, "File %s is missing", file.Path)
+		}
+	}
+
+	f.pairs(key+".headers", "header", end.Headers)
+	f.pairs(key+".parameters", "parameter", end.Parameters)
+	f.timeouts(key, end.Timeout, end.ConnectTimeout, end.ReadTimeout)
+	f.retry(key+".Retry", end.Retry)
+
+	for name, value := range map[string]string{
+		"uri":            end.URI,
+		"body":           end.Body,
+		"query":          end.Query,
+		"operation_name": end.OperationName,
+	} {
+		f.template(fmt.Sprintf("%s.%s", key, name), value)
+	}
+
+	for _, message := range end.Messages {
+		f.template(key+".messages", message)
+	}
+
+	for i, item := range end.Form {
+		f.template(fmt.Sprintf("%s.Form.%d.value", key, i), item.Value)
+		f.template(fmt.Sprintf("%s.Form.%d.file", key, i), item.File)
+	}
+
+	for name, value := range end.Variables {
+		f.variables(fmt.Sprintf("%s.variables.%s", key, name), value)
+	}
+}
+
+// pairs checks that headers or parameters are pairs of name and value
+func (f *lintFile) pairs(key, kind string, items [][]string) {
+	for _, item := range items {
+		if len(item) != 2 {
+			f.report(ErrorSeverity, key, "Invalid %s %s, use [name, value]", kind, pairString(item))
+			continue
+		}
+
+		f.template(key, item[1])
+	}
+}
+
+// timeouts checks the timeout, connect timeout and read timeout of a table
+func (f *lintFile) timeouts(table, timeout, connect, read string) {
+	for name, value := range map[string]string{
+		"timeout":         timeout,
+		"connect_timeout": connect,
+		"read_timeout":    read,
+	} {
+		if _, err := util.ParseTimeout(value); err != nil {
+			f.report(ErrorSeverity, fmt.Sprintf("%s.%s", table, name), "%s", err.Error())
+		}
+	}
+}
+
+// retry checks the backoff durations of a retry table
+func (f *lintFile) retry(table string, retry model.Retry) {
+	for name, value := range map[string]string{
+		"backoff":     retry.Backoff,
+		"max_backoff": retry.MaxBackoff,
+	} {
+		if value == "" {
+			continue
+		}
+
+		if _, err := time.ParseDuration(value); err != nil {
+			f.report(ErrorSeverity, fmt.Sprintf("%s.%s", table, name), "%s", err.Error())
+		}
+	}
+}
+
+// variables checks the placeholders of graphql variables recursively
+func (f *lintFile) variables(key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		f.template(key, v)
+	case map[string]interface{}:
+		for name, item := range v {
+			f.variables(fmt.Sprintf("%s.%s", key, name), item)
+		}
+	case []interface{}:
+		for _, item := range v {
+			f.variables(key, item)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			f.variables(key, item)
+		}
+	}
+}
+
+// template checks the placeholders syntax, the functions and the secret providers of a value
+func (f *lintFile) template(key, value string) {
+	tokens, err := Tokenize(value)
+
+	var terr *TemplateError
+
+	if errors.As(err, &terr) {
+		line, column := f.line(key), 0
+
+		if start, ok := f.values[key]; ok {
+			line = start[0] + terr.Line - 1
+			column = terr.Column
+
+			if terr.Line == 1 {
+				column = start[1] + terr.Column - 1
+			}
+		}
+
+		f.linter.Diagnostics = append(f.linter.Diagnostics, Diagnostic{
+			Path:     f.path,
+			Line:     line,
+			Column:   column,
+			Severity: ErrorSeverity,
+			Message:  terr.Message,
+		})
+		return
+	}
+
+	f.tokens(key, tokens)
+}
+
+// tokens checks the functions of tokens
+func (f *lintFile) tokens(key string, tokens []Token) {
+	for _, token := range tokens {
+		if token.Kind == FunctionToken {
+			function, ok := Functions[token.Name]
+
+			if !ok {
+				f.report(ErrorSeverity, key, "Unknown function %s in %s", token.Name, token.Raw)
+			} else if len(token.Args) > function.Args || len(token.Args) < function.Args-function.Optional {
+				f.report(ErrorSeverity, key, "Function %s expects %d arguments, got %d", token.Name, function.Args, len(token.Args))
+			}
+		}
+
+		for _, arg := range token.Args {
+			f.tokens(key, arg)
+		}
+	}
+}
+
+// report adds a diagnostic at the line of a key
+func (f *lintFile) report(severity, key, format string, args ...interface{}) {
+	f.linter.Diagnostics = append(f.linter.Diagnostics, Diagnostic{
+		Path:     f.path,
+		Line:     f.line(key),
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// line gets the line of a key or of its nearest parent table, 0 if not found
+func (f *lintFile) line(key string) int {
+	for key != "" {
+		if line, ok := f.lines[key]; ok {
+			return line
+		}
+
+		i := strings.LastIndex(key, ".")
+
+		if i < 0 {
+			break
+		}
+
+		key = key[:i]
+	}
+
+	return 0
+}
+
+// find gets the first indexed key matching a key without array indexes
+func (f *lintFile) find(key string) string {
+	result := ""
+
+	for item, line := range f.lines {
+		parts := []string{}
+
+		for _, part := range strings.Split(item, ".") {
+			if _, err := strconv.Atoi(part); err != nil {
+				parts = append(parts, part)
+			}
+		}
+
+		if strings.Join(parts, ".") == key && (result == "" || line < f.lines[result]) {
+			result = item
+		}
+	}
+
+	if result == "" {
+		return key
+	}
+
+	return result
+}
+
+// indexLines indexes the lines of the tables and keys of a TOML document
+//
+// Array tables get their index like Endpoint.0.method, the string values get the
+// line and column where their content starts.
+func indexLines(data string) (map[string]int, map[string][2]int) {
+	lines := make(map[string]int)
+	values := make(map[string][2]int)
+	arrays := make(map[string]int)
+	table := ""
+	multiline := ""
+
+	for i, text := range strings.Split(data, "\n") {
+		n := i + 1
+
+		if multiline != "" {
+			if strings.Count(text, multiline)%2 == 1 {
+				multiline = ""
+			}
+
+			continue
+		}
+
+		if m := arrayHeader.FindStringSubmatch(text); m != nil {
+			if _, ok := arrays[m[1]]; ok {
+				arrays[m[1]]++
+			} else {
+				arrays[m[1]] = 0
+			}
+
+			for k := range arrays {
+				if strings.HasPrefix(k, m[1]+".") {
+					delete(arrays, k)
+				}
+			}
+
+			table = tablePath(m[1], arrays)
+			lines[table] = n
+			continue
+		}
+
+		if m := tableHeader.FindStringSubmatch(text); m != nil {
+			table = tablePath(m[1], arrays)
+			lines[table] = n
+			continue
+		}
+
+		m := keyValue.FindStringSubmatch(text)
+
+		if m == nil {
+			continue
+		}
+
+		key := m[1]
+
+		if table != "" {
+			key = fmt.Sprintf("%s.%s", table, m[1])
+		}
+
+		lines[key] = n
+		value := m[2]
+		column := len(text) - len(value) + 1
+
+		for _, delim := range []string{`"""`, `'''`} {
+			if !strings.HasPrefix(value, delim) {
+				continue
+			}
+
+			if strings.Count(value, delim) == 1 {
+				multiline = delim
+			}
+
+			// The newline right after the delimiter is trimmed
+			if strings.TrimSpace(value) == delim {
+				values[key] = [2]int{n + 1, 1}
+			} else {
+				values[key] = [2]int{n, column + len(delim)}
+			}
+		}
+
+		if _, ok := values[key]; !ok && (strings.HasPrefix(value, `"`) || strings.HasPrefix(value, `'`)) {
+			values[key] = [2]int{n, column + 1}
+		}
+	}
+
+	return lines, values
+}
+
+// tablePath gets the path of a table with the indexes of the array tables
+func tablePath(name string, arrays map[string]int) string {
+	path := []string{}
+	parts := strings.Split(name, ".")
+
+	for i, part := range parts {
+		path = append(path, part)
+
+		if index, ok := arrays[strings.Join(parts[:i+1], ".")]; ok {
+			path = append(path, strconv.Itoa(index))
+		}
+	}
+
+	return strings.Join(path, ".")
+}
+
+// CheckPairs validates that the headers and parameters of a service are pairs of name and value
+func CheckPairs(service *model.Service) error {
+	security := map[string][]string{
+		"api_key": service.Security.APIKey.Header,
+		"bearer":  service.Security.Bearer.Header,
+		"basic":   service.Security.Basic.Header,
+	}
+
+	if header, ok := security[service.Security.Scheme]; ok && len(header) != 2 {
+		return fmt.Errorf("Invalid %s header %s, use [name, value]", service.Security.Scheme, pairString(header))
+	}
+
+	items := append([][]string{}, service.Main.Headers...)
+
+	for _, end := range service.Endpoint {
+		items = append(append(items, end.Headers...), end.Parameters...)
+	}
+
+	for _, item := range items {
+		if len(item) != 2 {
+			return fmt.Errorf("Invalid header or parameter %s, use [name, value]", pairString(item))
+		}
+	}
+
+	return nil
+}
+
+// pairString formats a header or parameter pair
+func pairString(item []string) string {
+	quoted := []string{}
+
+	for _, value := range item {
+		quoted = append(quoted, strconv.Quote(value))
+	}
+
+	return fmt.Sprintf("[%s]", strings.Join(quoted, ", "))
+}
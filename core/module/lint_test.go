@@ -0,0 +1,209 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/pkg"
+)
+
+// lintMessages gets the formatted diagnostics of a linter
+func lintMessages(linter *Linter) []string {
+	messages := []string{}
+
+	for _, item := range linter.Diagnostics {
+		messages = append(messages, item.String())
+	}
+
+	return messages
+}
+
+// TestLint test cases
+func TestLint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poodle")
+	pkg.Expect(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		pkg.Expect(t, nil, ioutil.WriteFile(path, []byte(data), 0600))
+		return path
+	}
+
+	t.Run("TestLintClean", func(t *testing.T) {
+		linter := NewLinter()
+		linter.Lint(write("clean.toml", `
+[Main]
+    id = "clean"
+    service_url = "{$serviceURL:http://127.0.0.1:8080}"
+    timeout = "30s"
+
+[Security]
+    scheme = "bearer"
+
+    [Security.Bearer]
+        header = ["Authorization", "Bearer {$!authToken}"]
+
+[[Endpoint]]
+    id = "CreateItem"
+    method = "post"
+    uri = "/item/{$id}"
+    headers = [ ["Content-Type", "application/json"] ]
+    body = """
+{"id": "{$uuid()}", "meta": {$meta:{"tags": []}}}
+"""
+`))
+
+		pkg.Expect(t, []string{}, lintMessages(linter))
+		pkg.Expect(t, 0, linter.Errors())
+	})
+
+	t.Run("TestLintSyntax", func(t *testing.T) {
+		linter := NewLinter()
+		linter.Lint(write("syntax.toml", "[Main]\nid = \"x\n"))
+
+		pkg.Expect(t, []string{
+			filepath.Join(dir, "syntax.toml") + ":2:8: error: strings cannot contain newlines",
+		}, lintMessages(linter))
+	})
+
+	t.Run("TestLintDefinition", func(t *testing.T) {
+		path := write("broken.toml", `[Main]
+    id = "clean"
+    service_url = "ftp://example.com"
+    timeout = "10x"
+    headers = [ ["Content-Type"] ]
+    protocl = "h2"
+
+[[Endpoint]]
+    id = "Get"
+    method = "purge"
+    uri = "/item"
+    parameters = [ ["a", "b", "c"] ]
+    public = true
+
+[[Endpoint]]
+    id = "Get"
+    method = "get"
+    body = """
+{
+  "id": "{$id"
+}
+"""
+    public = true
+
+[[Endpoint]]
+    id = "Now"
+    uri = "/{$nw()}/{$base64()}/{$secret:vault:key}"
+    public = true
+`)
+
+		linter := NewLinter()
+		linter.Lint(filepath.Join(dir, "clean.toml"))
+		linter.Lint(path)
+
+		messages := lintMessages(linter)
+
+		pkg.Expect(t, true, contains(messages, path+":2: error: Duplicate service id clean, also defined in "+filepath.Join(dir, "clean.toml")+":3"))
+		pkg.Expect(t, true, contains(messages, path+":3: error: Unknown scheme ftp of service url, use one of http, https, ws, wss, unix, http+unix"))
+		pkg.Expect(t, true, contains(messages, path+":4: error: Invalid timeout 10x: time: unknown unit \"x\" in duration \"10x\""))
+		pkg.Expect(t, true, contains(messages, path+":5: error: Invalid header [\"Content-Type\"], use [name, value]"))
+		pkg.Expect(t, true, contains(messages, path+":6: warning: Unknown key Main.protocl"))
+		pkg.Expect(t, true, contains(messages, path+":10: warning: Unknown HTTP method PURGE, custom verbs are sent as is"))
+		pkg.Expect(t, true, contains(messages, path+":12: error: Invalid parameter [\"a\", \"b\", \"c\"], use [name, value]"))
+		pkg.Expect(t, true, contains(messages, path+":13: warning: Endpoint is public but the service has no security scheme"))
+		pkg.Expect(t, true, contains(messages, path+":16: error: Duplicate endpoint id Get"))
+		pkg.Expect(t, true, contains(messages, path+":20:10: error: Unterminated placeholder"))
+		pkg.Expect(t, 2, countPrefix(messages, path+":27:"))
+		pkg.Expect(t, true, linter.Errors() > 0)
+	})
+
+	t.Run("TestLintFiles", func(t *testing.T) {
+		body := write("body.json", `{}`)
+
+		path := write("files.toml", `[Main]
+    id = ""
+    service_url = "http://127.0.0.1:8080"
+
+[[Endpoint]]
+    id = "Create"
+    method = "post"
+    body_file = "`+body+`"
+
+[[Endpoint]]
+    id = "Update"
+    method = "put"
+    body_file = "body.json"
+
+[[Endpoint]]
+    id = "Delete"
+    method = "delete"
+    body_file = "missing.json"
+`)
+
+		linter := NewLinter()
+		linter.Lint(path)
+
+		pkg.Expect(t, []string{
+			path + ":1: error: Missing service id",
+			path + ":18: error: File missing.json is missing",
+		}, lintMessages(linter))
+	})
+
+	t.Run("TestLintProjectConfig", func(t *testing.T) {
+		linter := NewLinter()
+		linter.Lint(write(ProjectFile, "[General]\n    editor = \"vim\"\n"))
+
+		pkg.Expect(t, []string{}, lintMessages(linter))
+	})
+
+	t.Run("TestCheckPairs", func(t *testing.T) {
+		service := model.NewEmptyService("api")
+		service.Main.Headers = [][]string{{"Accept", "application/json"}}
+		service.Security.Scheme = "api_key"
+		service.Security.APIKey.Header = []string{"X-API-KEY"}
+
+		pkg.Expect(t, `Invalid api_key header ["X-API-KEY"], use [name, value]`, CheckPairs(service).Error())
+
+		service.Security.APIKey.Header = []string{"X-API-KEY", "{$!apiKey}"}
+		service.Endpoint = []model.Endpoint{{ID: "Get", Parameters: [][]string{{"q"}}}}
+
+		pkg.Expect(t, `Invalid header or parameter ["q"], use [name, value]`, CheckPairs(service).Error())
+
+		service.Endpoint[0].Parameters = [][]string{{"q", "{$q}"}}
+
+		pkg.Expect(t, nil, CheckPairs(service))
+	})
+}
+
+// contains checks if a message was reported
+func contains(messages []string, message string) bool {
+	for _, item := range messages {
+		if item == message {
+			return true
+		}
+	}
+
+	return false
+}
+
+// countPrefix counts the messages starting with a prefix
+func countPrefix(messages []string, prefix string) int {
+	count := 0
+
+	for _, item := range messages {
+		if strings.HasPrefix(item, prefix) {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Middleware wraps a round tripper to act on requests and responses
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc turns a function into a round tripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls the function
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps the round tripper with the middlewares, the first middleware is the outermost
+func Chain(transport http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		transport = middlewares[i](transport)
+	}
+
+	return transport
+}
+
+// LoggingMiddleware logs requests and responses in debug mode with the secret values redacted
+func LoggingMiddleware(redactor *Redactor) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			fields := log.Fields{
+				"method":   req.Method,
+				"url":      redactor.Redact(req.URL.String()),
+				"duration": time.Since(start).String(),
+			}
+
+			if err != nil {
+				log.WithFields(fields).Debugf("Request failed: %s", redactor.Redact(err.Error()))
+				return resp, err
+			}
+
+			fields["status"] = resp.StatusCode
+			log.WithFields(fields).Debug("Request sent")
+
+			return resp, nil
+		})
+	}
+}
+
+// AuthMiddleware adds the headers to requests sent to a host if missing
+//
+// Requests to other hosts, like redirects, never get the headers.
+func AuthMiddleware(host string, headers map[string]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Host != host {
+				return next.RoundTrip(req)
+			}
+
+			req = req.Clone(req.Context())
+
+			for k, v := range headers {
+				if req.Header.Get(k) == "" {
+					req.Header.Set(k, v)
+				}
+			}
+
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// ReadTimeoutMiddleware aborts requests if the response headers don't arrive in time
+func ReadTimeoutMiddleware(timeout time.Duration) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		if timeout <= 0 {
+			return next
+		}
+
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx, cancel := context.WithCancel(req.Context())
+			timer := time.AfterFunc(timeout, cancel)
+
+			resp, err := next.RoundTrip(req.WithContext(ctx))
+
+			if !timer.Stop() {
+				cancel()
+
+				if resp != nil {
+					resp.Body.Close()
+				}
+
+				return nil, fmt.Errorf("Timeout awaiting response headers after %s", timeout)
+			}
+
+			if err != nil {
+				cancel()
+				return resp, err
+			}
+
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+			return resp, nil
+		})
+	}
+}
+
+// cancelBody releases the request context once the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and releases the context
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
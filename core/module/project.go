@@ -0,0 +1,260 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/core/util"
+)
+
+const (
+	// ProjectFile is the project definition file
+	ProjectFile = ".poodle.toml"
+	// ProjectDir is the project directory of definitions
+	ProjectDir = ".poodle"
+	// ProjectConfigFile is the config file inside the project directory
+	ProjectConfigFile = "config.toml"
+
+	// GlobalSource is the label of the services directory definitions
+	GlobalSource = "global"
+	// ProjectSource is the label of the project definitions
+	ProjectSource = "project"
+)
+
+// Project is a directory holding a .poodle.toml file, a .poodle directory or both
+type Project struct {
+	Root string
+	File string
+	Dir  string
+}
+
+// Definition is a service definition with the source it was loaded from
+type Definition struct {
+	Source string
+	// Root is the directory the definition was loaded from, its subfolders are namespaces
+	Root    string
+	Path    string
+	Service *model.Service
+}
+
+// FindProject walks up from a directory to find the nearest project
+//
+// The walk stops at the repository root, the first directory with a .git entry,
+// or at the file system root. A nil project is returned if none is found.
+func FindProject(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		project := &Project{Root: dir}
+
+		if util.FileExists(filepath.Join(dir, ProjectFile)) {
+			project.File = filepath.Join(dir, ProjectFile)
+		}
+
+		if util.DirExists(filepath.Join(dir, ProjectDir)) {
+			project.Dir = filepath.Join(dir, ProjectDir)
+		}
+
+		if project.File != "" || project.Dir != "" {
+			return project, nil
+		}
+
+		parent := filepath.Dir(dir)
+
+		if util.PathExists(filepath.Join(dir, ".git")) || parent == dir {
+			return nil, nil
+		}
+
+		dir = parent
+	}
+}
+
+// ConfigFiles gets the project config files, the .poodle.toml file then the
+// config file of the .poodle directory
+func (p *Project) ConfigFiles() []string {
+	files := []string{}
+
+	if p.File != "" {
+		files = append(files, p.File)
+	}
+
+	if p.Dir != "" && util.FileExists(filepath.Join(p.Dir, ProjectConfigFile)) {
+		files = append(files, filepath.Join(p.Dir, ProjectConfigFile))
+	}
+
+	return files
+}
+
+// Files gets the project definition files, the .poodle.toml file then the files
+// of the .poodle directory except its config file
+func (p *Project) Files() ([]string, error) {
+	files := []string{}
+
+	if p.File != "" {
+		files = append(files, p.File)
+	}
+
+	if p.Dir == "" {
+		return files, nil
+	}
+
+	items, err := DefinitionFiles(p.Dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range items {
+		if path != filepath.Join(p.Dir, ProjectConfigFile) {
+			files = append(files, path)
+		}
+	}
+
+	return files, nil
+}
+
+// Definitions loads the project service definitions
+//
+// The .poodle.toml file is a definition only if it has endpoints since it may
+// hold the project config alone.
+func (p *Project) Definitions() ([]Definition, error) {
+	files, err := p.Files()
+
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := []Definition{}
+
+	for _, path := range files {
+		definition, err := LoadDefinition(ProjectSource, path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if path == p.File && len(definition.Service.Endpoint) == 0 {
+			continue
+		}
+
+		if path != p.File {
+			definition.Root = p.Dir
+		}
+
+		definitions = append(definitions, definition)
+	}
+
+	return definitions, nil
+}
+
+// LoadDefinitions loads the service definitions under a directory and its subdirectories
+func LoadDefinitions(source, dir string) ([]Definition, error) {
+	files, err := DefinitionFiles(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := []Definition{}
+
+	for _, path := range files {
+		definition, err := LoadDefinition(source, path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		definition.Root = util.RemoveTrailingSlash(dir)
+		definitions = append(definitions, definition)
+	}
+
+	return definitions, nil
+}
+
+// DefinitionFiles lists the .toml files under a directory and its subdirectories sorted by path
+func DefinitionFiles(dir string) ([]string, error) {
+	files, err := util.ListFiles(util.EnsureTrailingSlash(dir))
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Unable to list services under %s: %s",
+			util.EnsureTrailingSlash(dir),
+			err.Error(),
+		)
+	}
+
+	paths := []string{}
+
+	for _, v := range files {
+		if strings.HasSuffix(v.Name, ".toml") {
+			paths = append(paths, v.Path)
+		}
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// LoadDefinition loads a service definition file
+func LoadDefinition(source, path string) (Definition, error) {
+	service := model.NewEmptyService(filepath.Base(path))
+
+	if err := service.Decode(path); err != nil {
+		return Definition{}, fmt.Errorf("Unable to decode service %s: %s", path, err.Error())
+	}
+
+	return Definition{
+		Source:  source,
+		Root:    filepath.Dir(path),
+		Path:    path,
+		Service: service,
+	}, nil
+}
+
+// Namespace gets the folder of the definition under its root like team/payments
+func (d Definition) Namespace() string {
+	rel, err := filepath.Rel(d.Root, filepath.Dir(d.Path))
+
+	if err != nil || rel == "." {
+		return ""
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// Name gets the path of the definition under its root without the extension
+func (d Definition) Name() string {
+	name := strings.TrimSuffix(filepath.Base(d.Path), filepath.Ext(d.Path))
+
+	if d.Namespace() == "" {
+		return name
+	}
+
+	return fmt.Sprintf("%s/%s", d.Namespace(), name)
+}
+
+// Label gets the label of an endpoint or a service in the finder with the
+// source and the folder namespace
+func (d Definition) Label(id string) string {
+	if d.Namespace() != "" {
+		id = fmt.Sprintf("%s/%s", d.Namespace(), id)
+	}
+
+	if d.Source == "" {
+		return id
+	}
+
+	return fmt.Sprintf("[%s] %s", d.Source, id)
+}
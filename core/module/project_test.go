@@ -0,0 +1,149 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/core/util"
+	"github.com/clivern/poodle/pkg"
+)
+
+// TestProject test cases
+func TestProject(t *testing.T) {
+	t.Run("TestFindProject", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		dir, err = filepath.EvalSymlinks(dir)
+		pkg.Expect(t, nil, err)
+
+		repo := filepath.Join(dir, "repo")
+		nested := filepath.Join(repo, "src", "api")
+
+		pkg.Expect(t, nil, os.MkdirAll(nested, 0755))
+		pkg.Expect(t, nil, os.MkdirAll(filepath.Join(repo, ".git"), 0755))
+
+		// Projects above the repository root are ignored
+		pkg.Expect(t, nil, ioutil.WriteFile(filepath.Join(dir, ProjectFile), []byte(""), 0600))
+
+		project, err := FindProject(nested)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, true, project == nil)
+
+		pkg.Expect(t, nil, os.MkdirAll(filepath.Join(repo, ProjectDir, "payments"), 0755))
+		pkg.Expect(t, nil, ioutil.WriteFile(filepath.Join(repo, ProjectFile), []byte(`
+[General]
+    redact_headers = ["X-Token"]
+
+[HTTP]
+    max_conns_per_host = 8
+
+[Services]
+    directory = "definitions"
+
+[Gist]
+    access_token = "leaked"
+
+[Main]
+    id = "local"
+    service_url = "http://127.0.0.1:8080"
+
+[[Endpoint]]
+    id = "Health"
+    uri = "/_health"
+`), 0600))
+		pkg.Expect(t, nil, ioutil.WriteFile(filepath.Join(repo, ProjectDir, ProjectConfigFile), []byte(`
+[General]
+    editor = "vim"
+`), 0600))
+		pkg.Expect(t, nil, ioutil.WriteFile(filepath.Join(repo, ProjectDir, "payments", "api.toml"), []byte(`
+[Main]
+    id = "payments"
+
+[[Endpoint]]
+    id = "GetPayment"
+    uri = "/payment/{$id}"
+`), 0600))
+
+		project, err = FindProject(nested)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, repo, project.Root)
+		pkg.Expect(t, filepath.Join(repo, ProjectFile), project.File)
+		pkg.Expect(t, filepath.Join(repo, ProjectDir), project.Dir)
+		pkg.Expect(t, []string{
+			filepath.Join(repo, ProjectFile),
+			filepath.Join(repo, ProjectDir, ProjectConfigFile),
+		}, project.ConfigFiles())
+
+		definitions, err := project.Definitions()
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 2, len(definitions))
+		pkg.Expect(t, "local", definitions[0].Service.Main.ID)
+		pkg.Expect(t, "payments", definitions[1].Service.Main.ID)
+		pkg.Expect(t, "[project] payments/payments - GetPayment", definitions[1].Label("payments - GetPayment"))
+		pkg.Expect(t, "payments - GetPayment", Definition{}.Label("payments - GetPayment"))
+
+		conf := model.NewConfigs()
+		conf.Gist.AccessToken = "token"
+
+		for _, path := range project.ConfigFiles() {
+			pkg.Expect(t, nil, conf.Override(path))
+		}
+
+		pkg.Expect(t, "vim", conf.General.Editor)
+		pkg.Expect(t, 40, conf.General.Column)
+		pkg.Expect(t, []string{"X-Token"}, conf.General.RedactHeaders)
+		pkg.Expect(t, 8, conf.HTTP.MaxConnsPerHost)
+		pkg.Expect(t, filepath.Join(repo, "definitions"), conf.Services.Directory)
+		pkg.Expect(t, "token", conf.Gist.AccessToken)
+	})
+
+	t.Run("TestLoadDefinitions", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		service := []byte("[Main]\n    id = \"api\"\n\n[[Endpoint]]\n    id = \"Get\"\n")
+
+		pkg.Expect(t, nil, os.MkdirAll(filepath.Join(dir, "team", "payments"), 0755))
+		pkg.Expect(t, nil, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+		pkg.Expect(t, nil, ioutil.WriteFile(filepath.Join(dir, "root.toml"), service, 0600))
+		pkg.Expect(t, nil, ioutil.WriteFile(filepath.Join(dir, "team", "payments", "api.toml"), service, 0600))
+		pkg.Expect(t, nil, ioutil.WriteFile(filepath.Join(dir, ".git", "config.toml"), []byte("invalid = "), 0600))
+
+		definitions, err := LoadDefinitions(GlobalSource, dir+"/")
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 2, len(definitions))
+		pkg.Expect(t, "", definitions[0].Namespace())
+		pkg.Expect(t, "root", definitions[0].Name())
+		pkg.Expect(t, "[global] api - Get", definitions[0].Label("api - Get"))
+		pkg.Expect(t, "team/payments", definitions[1].Namespace())
+		pkg.Expect(t, "team/payments/api", definitions[1].Name())
+		pkg.Expect(t, "[global] team/payments/api - Get", definitions[1].Label("api - Get"))
+
+		local := NewFileSystem()
+		pkg.Expect(t, nil, local.LoadFromLocal(dir, "toml"))
+		pkg.Expect(t, 2, len(local.Files))
+
+		target, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(target)
+
+		pkg.Expect(t, nil, local.DumpLocally(target))
+		pkg.Expect(t, true, util.FileExists(filepath.Join(target, "team", "payments", "api.toml")))
+
+		conf := model.NewConfigs()
+		conf.Services.Directory = dir + "/"
+		conf.Services.Directories = []string{dir, target, ""}
+
+		pkg.Expect(t, []string{dir, target}, conf.Services.Paths())
+	})
+}
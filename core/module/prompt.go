@@ -6,6 +6,7 @@ package module
 
 import (
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/manifoldco/promptui"
@@ -13,6 +14,8 @@ import (
 
 // Prompt struct
 type Prompt struct {
+	// Stdin overrides the input to read from, useful if stdin is already consumed
+	Stdin io.ReadCloser
 }
 
 // NotEmpty returns error if input is empty
@@ -50,6 +53,34 @@ func (p *Prompt) Input(label string, validate promptui.ValidateFunc) (string, er
 		Label:     label,
 		Templates: templates,
 		Validate:  validate,
+		Stdin:     p.Stdin,
+	}
+
+	result, err := item.Run()
+
+	if err != nil {
+		return "", err
+	}
+
+	return result, nil
+}
+
+// Secret request a secret value from end user without echoing it
+func (p *Prompt) Secret(label string, validate promptui.ValidateFunc) (string, error) {
+
+	templates := &promptui.PromptTemplates{
+		Prompt:  "{{ . }} ",
+		Valid:   "{{ . | green }} ",
+		Invalid: "{{ . | red }} ",
+		Success: "{{ . | bold }} ",
+	}
+
+	item := promptui.Prompt{
+		Label:     label,
+		Templates: templates,
+		Validate:  validate,
+		Mask:      '*',
+		Stdin:     p.Stdin,
 	}
 
 	result, err := item.Run()
@@ -67,6 +98,7 @@ func (p *Prompt) Select(label string, items []string) (string, error) {
 	item := promptui.Select{
 		Label: label,
 		Items: items,
+		Stdin: p.Stdin,
 	}
 
 	_, result, err := item.Run()
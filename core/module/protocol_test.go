@@ -0,0 +1,99 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/pkg"
+)
+
+// TestProtocol test cases
+func TestProtocol(t *testing.T) {
+	t.Run("TestProtocolH2C", func(t *testing.T) {
+		srv := pkg.H2CServerMock("/", `{"status":"ok"}`, http.StatusOK)
+		defer srv.Close()
+
+		for _, item := range []struct {
+			Protocol string
+			Proto    string
+			ALPN     string
+		}{
+			{ProtocolAuto, "HTTP/1.1", "none (cleartext)"},
+			{ProtocolHTTP1, "HTTP/1.1", "none (cleartext)"},
+			{ProtocolH2C, "HTTP/2.0", "none (h2c prior knowledge)"},
+		} {
+			httpClient := NewHTTPClient()
+			httpClient.Protocol = item.Protocol
+			caller := NewCaller(httpClient)
+
+			response, err := httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{})
+			pkg.Expect(t, nil, err)
+			pkg.Expect(t, item.Proto, response.Proto)
+			pkg.Expect(t, item.Proto, response.Header.Get("X-Request-Proto"))
+
+			output := caller.Pretty(response)
+			pkg.Expect(t, true, strings.Contains(output, item.ALPN))
+		}
+	})
+
+	t.Run("TestProtocolH2", func(t *testing.T) {
+		srv := pkg.H2ServerMock("/", `{"status":"ok"}`, http.StatusOK)
+		defer srv.Close()
+
+		config := NewTransportConfig()
+		config.TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+		for _, item := range []struct {
+			Protocol string
+			Proto    string
+			ALPN     string
+		}{
+			{ProtocolAuto, "HTTP/2.0", "ALPN:\x1b[0m h2"},
+			{ProtocolHTTP1, "HTTP/1.1", "none (not negotiated)"},
+			{ProtocolH2, "HTTP/2.0", "ALPN:\x1b[0m h2"},
+		} {
+			transport, err := NewProtocolTransport(item.Protocol, config)
+			pkg.Expect(t, nil, err)
+
+			httpClient := NewHTTPClient()
+			httpClient.Transport = transport
+			caller := NewCaller(httpClient)
+
+			response, err := httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{})
+			pkg.Expect(t, nil, err)
+			pkg.Expect(t, item.Proto, response.Proto)
+
+			output := caller.Pretty(response)
+			pkg.Expect(t, true, strings.Contains(output, item.ALPN))
+		}
+	})
+
+	t.Run("TestServiceProtocol", func(t *testing.T) {
+		srv := pkg.H2CServerMock("/", `{"status":"ok"}`, http.StatusOK)
+		defer srv.Close()
+
+		service := &model.Service{}
+		service.Main.ID = "h2c_service"
+		service.Main.ServiceURL = srv.URL
+		service.Main.Protocol = ProtocolH2C
+		service.Endpoint = []model.Endpoint{{ID: "status", URI: "/", Method: http.MethodGet}}
+
+		caller := NewCaller(NewHTTPClient())
+
+		response, err := caller.Call("h2c_service - status", service, map[string]Field{})
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "HTTP/2.0", response.Proto)
+		caller.HTTPClient.DrainAndClose(response)
+
+		service.Main.Protocol = "h3"
+		_, err = caller.Call("h2c_service - status", service, map[string]Field{})
+		pkg.Expect(t, true, err != nil)
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	b64 "encoding/base64"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/clivern/poodle/core/util"
+)
+
+// Redactor replaces secret values with the redacted value
+//
+// A nil redactor leaves the data as is.
+type Redactor struct {
+	values []string
+
+	sync.RWMutex
+}
+
+// NewRedactor creates an instance of redactor
+func NewRedactor() *Redactor {
+	return &Redactor{}
+}
+
+// Add adds secret values to redact with their escaped forms
+func (r *Redactor) Add(values ...string) {
+	if r == nil {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+
+		for _, item := range []string{
+			value,
+			url.QueryEscape(value),
+			url.PathEscape(value),
+			escapeJSON(value),
+			b64.StdEncoding.EncodeToString([]byte(value)),
+		} {
+			if !util.InArray(item, r.values) {
+				r.values = append(r.values, item)
+			}
+		}
+	}
+
+	// Longer values first so that a secret containing another one is fully redacted
+	sort.SliceStable(r.values, func(i, j int) bool {
+		return len(r.values[i]) > len(r.values[j])
+	})
+}
+
+// Redact replaces the secret values in data
+func (r *Redactor) Redact(data string) string {
+	if r == nil {
+		return data
+	}
+
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, value := range r.values {
+		data = strings.Replace(data, value, RedactedValue, -1)
+	}
+
+	return data
+}
+
+// Writer wraps a writer to redact the secret values of each write
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return &redactWriter{redactor: r, next: w}
+}
+
+// redactWriter redacts secret values before writing
+type redactWriter struct {
+	redactor *Redactor
+	next     io.Writer
+}
+
+// Write writes the data with the secret values redacted
+func (w *redactWriter) Write(data []byte) (int, error) {
+	if _, err := io.WriteString(w.next, w.redactor.Redact(string(data))); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/pkg"
+)
+
+// TestRedact test cases
+func TestRedact(t *testing.T) {
+	t.Run("TestRedactor", func(t *testing.T) {
+		var empty *Redactor
+		pkg.Expect(t, "s3cr3t", empty.Redact("s3cr3t"))
+
+		redactor := NewRedactor()
+		redactor.Add("", "s3cr3t", "a b&c")
+
+		pkg.Expect(t, "token=[REDACTED]", redactor.Redact("token=s3cr3t"))
+		pkg.Expect(t, "q=[REDACTED] p=[REDACTED] raw=[REDACTED]", redactor.Redact("q=a+b%26c p=a%20b&c raw=a b&c"))
+		pkg.Expect(t, "Basic [REDACTED]", redactor.Redact("Basic czNjcjN0"))
+
+		output := &bytes.Buffer{}
+		fmt.Fprintf(redactor.Writer(output), "key %s\n", "s3cr3t")
+		pkg.Expect(t, "key [REDACTED]\n", output.String())
+	})
+
+	t.Run("TestRedactSecretFields", func(t *testing.T) {
+		srv := pkg.ServerMock("/items", `{"echo": "s3cr3t"}`, http.StatusOK)
+		defer srv.Close()
+
+		service := &model.Service{}
+		service.Main.ID = "secrets"
+		service.Main.ServiceURL = srv.URL
+		service.Security.Scheme = "api_key"
+		service.Security.APIKey.Header = []string{"X-Token", "{$!authApiKey}"}
+		service.Variables = map[string]model.Variable{
+			"password": {Secret: true, Description: "Account password"},
+		}
+		service.Endpoint = []model.Endpoint{{
+			ID:         "list",
+			Method:     http.MethodGet,
+			URI:        "/items",
+			Parameters: [][]string{{"key", "{$!authApiKey}"}, {"password", "{$password:changeme}"}},
+		}}
+
+		caller := NewCaller(NewHTTPClient())
+		fields := caller.GetFields("secrets - list", service)
+
+		pkg.Expect(t, 2, len(fields))
+		pkg.Expect(t, true, fields["authApiKey"].IsSecret())
+		pkg.Expect(t, true, fields["password"].IsSecret())
+		pkg.Expect(t, false, strings.Contains(fields["password"].Prompt, "changeme"))
+
+		fields["authApiKey"] = Field{Value: "s3cr3t", Variable: fields["authApiKey"].Variable}
+		fields["password"] = Field{IsOptional: true, Default: "changeme", Value: "changeme", Variable: fields["password"].Variable}
+
+		output := &bytes.Buffer{}
+		caller.HTTPClient.Dumper = NewDumper(output, []string{}, true)
+		caller.HTTPClient.Dumper.Redactor = caller.HTTPClient.Redactor
+
+		response, err := caller.CallWithContext(context.TODO(), "secrets - list", service, fields)
+		pkg.Expect(t, nil, err)
+
+		pretty := caller.Pretty(response)
+		pkg.Expect(t, false, strings.Contains(pretty, "s3cr3t"))
+		pkg.Expect(t, true, strings.Contains(pretty, `{"echo": "[REDACTED]"}`))
+
+		dump := output.String()
+		pkg.Expect(t, false, strings.Contains(dump, "s3cr3t"))
+		pkg.Expect(t, false, strings.Contains(dump, "changeme"))
+		pkg.Expect(t, true, strings.Contains(dump, "X-Token: [REDACTED]"))
+	})
+}
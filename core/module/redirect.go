@@ -0,0 +1,156 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	. "github.com/logrusorgru/aurora/v3"
+)
+
+// DefaultMaxRedirects is the max redirects to follow if not configured
+const DefaultMaxRedirects = 10
+
+// redirectsKey is the context key of the redirect chain
+type redirectsKey struct{}
+
+// RedirectPolicy struct
+type RedirectPolicy struct {
+	Follow  bool
+	MaxHops int
+	// KeepAuth sends the auth headers to other hosts while redirecting
+	KeepAuth bool
+	// AuthHeaders are the headers to drop on redirects to other hosts unless kept
+	AuthHeaders []string
+}
+
+// RedirectHop struct
+type RedirectHop struct {
+	StatusCode int
+	From       string
+	Location   string
+}
+
+// RedirectChain struct
+type RedirectChain struct {
+	Hops    []RedirectHop
+	Stopped bool
+
+	sync.Mutex
+}
+
+// NewRedirectPolicy creates a redirect policy that follows redirects like browsers do
+func NewRedirectPolicy() *RedirectPolicy {
+	return &RedirectPolicy{
+		Follow:      true,
+		MaxHops:     DefaultMaxRedirects,
+		AuthHeaders: []string{"Authorization"},
+	}
+}
+
+// WithRedirects attaches a redirect chain to the context
+func WithRedirects(ctx context.Context, chain *RedirectChain) context.Context {
+	return context.WithValue(ctx, redirectsKey{}, chain)
+}
+
+// GetRedirects gets the redirect chain of the request that got the response
+func GetRedirects(response *http.Response) (*RedirectChain, bool) {
+	if response == nil || response.Request == nil {
+		return nil, false
+	}
+
+	chain, ok := response.Request.Context().Value(redirectsKey{}).(*RedirectChain)
+
+	return chain, ok
+}
+
+// CheckRedirect records the redirect and decides whether to follow it
+func (r *RedirectPolicy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if r == nil {
+		r = NewRedirectPolicy()
+	}
+
+	chain, _ := req.Context().Value(redirectsKey{}).(*RedirectChain)
+	previous := via[len(via)-1]
+
+	if chain != nil {
+		hop := RedirectHop{
+			From:     previous.URL.String(),
+			Location: req.URL.String(),
+		}
+
+		if req.Response != nil {
+			hop.StatusCode = req.Response.StatusCode
+		}
+
+		chain.Lock()
+		chain.Hops = append(chain.Hops, hop)
+		chain.Unlock()
+	}
+
+	if !r.Follow || len(via) > r.MaxHops {
+		if chain != nil {
+			chain.Lock()
+			chain.Stopped = true
+			chain.Unlock()
+		}
+
+		return http.ErrUseLastResponse
+	}
+
+	if req.URL.Hostname() == via[0].URL.Hostname() {
+		return nil
+	}
+
+	if r.KeepAuth {
+		for _, key := range append([]string{"Authorization", "Cookie"}, r.AuthHeaders...) {
+			if req.Header.Get(key) == "" && via[0].Header.Get(key) != "" {
+				req.Header[http.CanonicalHeaderKey(key)] = via[0].Header.Values(key)
+			}
+		}
+
+		return nil
+	}
+
+	for _, key := range r.AuthHeaders {
+		req.Header.Del(key)
+	}
+
+	return nil
+}
+
+// Pretty returns colored redirect chain
+func (c *RedirectChain) Pretty() string {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.Hops) == 0 {
+		return ""
+	}
+
+	value := fmt.Sprintf("\n%s\n", Magenta("Redirects"))
+
+	for i, hop := range c.Hops {
+		status := ""
+
+		if c.Stopped && i == len(c.Hops)-1 {
+			status = fmt.Sprintf(" %s", Red("(not followed)"))
+		}
+
+		value = value + fmt.Sprintf(
+			"%s %s %s %s%s\n",
+			Blue(hop.StatusCode),
+			hop.From,
+			Cyan("->"),
+			hop.Location,
+			status,
+		)
+	}
+
+	return value
+}
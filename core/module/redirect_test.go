@@ -0,0 +1,97 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clivern/poodle/pkg"
+)
+
+// TestRedirectPolicy test cases
+func TestRedirectPolicy(t *testing.T) {
+	t.Run("TestRedirectPolicy", func(t *testing.T) {
+		final := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Got-Authorization", r.Header.Get("Authorization"))
+			w.Header().Set("X-Got-Api-Key", r.Header.Get("X-API-KEY"))
+		}))
+		defer final.Close()
+
+		// Use another host name to cross hosts
+		finalURL := strings.Replace(final.URL, "127.0.0.1", "localhost", 1)
+
+		start := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/start" {
+				http.Redirect(w, r, "/next", http.StatusFound)
+				return
+			}
+			http.Redirect(w, r, finalURL+"/final", http.StatusMovedPermanently)
+		}))
+		defer start.Close()
+
+		headers := map[string]string{"Authorization": "Bearer abc", "X-API-KEY": "key"}
+		httpClient := NewHTTPClient()
+		httpClient.Redirect = NewRedirectPolicy()
+		httpClient.Redirect.AuthHeaders = append(httpClient.Redirect.AuthHeaders, "X-API-KEY")
+
+		response, err := httpClient.Get(context.TODO(), start.URL+"/start", map[string]string{}, headers)
+		pkg.Expect(t, nil, err)
+		httpClient.ToString(response)
+
+		chain, ok := GetRedirects(response)
+
+		pkg.Expect(t, true, ok)
+		pkg.Expect(t, http.StatusOK, response.StatusCode)
+		pkg.Expect(t, 2, len(chain.Hops))
+		pkg.Expect(t, http.StatusFound, chain.Hops[0].StatusCode)
+		pkg.Expect(t, start.URL+"/next", chain.Hops[0].Location)
+		pkg.Expect(t, http.StatusMovedPermanently, chain.Hops[1].StatusCode)
+		pkg.Expect(t, finalURL+"/final", chain.Hops[1].Location)
+		pkg.Expect(t, "", response.Header.Get("X-Got-Authorization"))
+		pkg.Expect(t, "", response.Header.Get("X-Got-Api-Key"))
+		pkg.Expect(t, true, strings.Contains(chain.Pretty(), finalURL+"/final"))
+
+		// Keep auth headers across hosts
+		httpClient.Redirect.KeepAuth = true
+
+		response, err = httpClient.Get(context.TODO(), start.URL+"/start", map[string]string{}, headers)
+		pkg.Expect(t, nil, err)
+		httpClient.ToString(response)
+
+		pkg.Expect(t, "Bearer abc", response.Header.Get("X-Got-Authorization"))
+		pkg.Expect(t, "key", response.Header.Get("X-Got-Api-Key"))
+
+		// Max hops reached
+		httpClient.Redirect.MaxHops = 1
+
+		response, err = httpClient.Get(context.TODO(), start.URL+"/start", map[string]string{}, headers)
+		pkg.Expect(t, nil, err)
+		httpClient.ToString(response)
+
+		chain, _ = GetRedirects(response)
+
+		pkg.Expect(t, http.StatusMovedPermanently, response.StatusCode)
+		pkg.Expect(t, true, chain.Stopped)
+		pkg.Expect(t, 2, len(chain.Hops))
+
+		// Don't follow
+		httpClient.Redirect.Follow = false
+
+		response, err = httpClient.Get(context.TODO(), start.URL+"/start", map[string]string{}, headers)
+		pkg.Expect(t, nil, err)
+		httpClient.ToString(response)
+
+		chain, _ = GetRedirects(response)
+
+		pkg.Expect(t, http.StatusFound, response.StatusCode)
+		pkg.Expect(t, "/next", response.Header.Get("Location"))
+		pkg.Expect(t, 1, len(chain.Hops))
+		pkg.Expect(t, true, strings.Contains(chain.Pretty(), "not followed"))
+	})
+}
@@ -0,0 +1,157 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clivern/poodle/core/util"
+)
+
+// DefaultRetryStatuses are the status codes retried if none configured
+var DefaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// IdempotentMethods are the methods safe to retry
+var IdempotentMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodTrace,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// RetryPolicy struct
+type RetryPolicy struct {
+	MaxAttempts   int
+	Backoff       time.Duration
+	MaxBackoff    time.Duration
+	Statuses      []int
+	NetworkErrors bool
+	NonIdempotent bool
+}
+
+// RetryAttempt struct
+type RetryAttempt struct {
+	Number     int
+	StatusCode int
+	Err        error
+	Wait       time.Duration
+}
+
+// NewRetryPolicy creates a retry policy with sane defaults
+func NewRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:   3,
+		Backoff:       500 * time.Millisecond,
+		MaxBackoff:    10 * time.Second,
+		Statuses:      DefaultRetryStatuses,
+		NetworkErrors: true,
+	}
+}
+
+// ShouldRetry decides if a failed attempt is retried and how long to wait before
+//
+// Non idempotent methods are retried only if allowed by the policy or
+// if the request carries an Idempotency-Key header. Responses asking to wait
+// longer than the max backoff with Retry-After are not retried.
+func (r *RetryPolicy) ShouldRetry(req *http.Request, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if r == nil || attempt >= r.MaxAttempts {
+		return 0, false
+	}
+
+	if !r.NonIdempotent && !util.InArray(req.Method, IdempotentMethods) && req.Header.Get("Idempotency-Key") == "" {
+		return 0, false
+	}
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || req.Context().Err() != nil {
+			return 0, false
+		}
+
+		return r.Delay(attempt, nil), r.NetworkErrors
+	}
+
+	statuses := r.Statuses
+
+	if len(statuses) == 0 {
+		statuses = DefaultRetryStatuses
+	}
+
+	if !util.InArray(resp.StatusCode, statuses) {
+		return 0, false
+	}
+
+	if wait, ok := RetryAfter(resp); ok && r.MaxBackoff > 0 && wait > r.MaxBackoff {
+		return 0, false
+	}
+
+	return r.Delay(attempt, resp), true
+}
+
+// Delay returns the wait before the next attempt
+//
+// It honors the Retry-After header otherwise it uses exponential backoff
+// with jitter, the wait is a random value between half and the full backoff.
+func (r *RetryPolicy) Delay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := RetryAfter(resp); ok {
+			return wait
+		}
+	}
+
+	backoff := r.Backoff
+
+	for i := 1; i < attempt && (r.MaxBackoff <= 0 || backoff < r.MaxBackoff); i++ {
+		backoff *= 2
+	}
+
+	if r.MaxBackoff > 0 && backoff > r.MaxBackoff {
+		backoff = r.MaxBackoff
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	half := backoff / 2
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// RetryAfter parses the Retry-After header in seconds or http date format
+func RetryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+
+		if wait < 0 {
+			wait = 0
+		}
+
+		return wait, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/clivern/poodle/pkg"
+)
+
+// TestRetryPolicy test cases
+func TestRetryPolicy(t *testing.T) {
+	t.Run("TestRetryPolicy", func(t *testing.T) {
+		policy := NewRetryPolicy()
+		policy.Backoff = 100 * time.Millisecond
+		policy.MaxBackoff = 300 * time.Millisecond
+
+		for attempt, max := range map[int]time.Duration{
+			1: 100 * time.Millisecond,
+			2: 200 * time.Millisecond,
+			3: 300 * time.Millisecond,
+			6: 300 * time.Millisecond,
+		} {
+			wait := policy.Delay(attempt, nil)
+			pkg.Expect(t, true, wait >= max/2 && wait <= max)
+		}
+
+		response := &http.Response{Header: http.Header{}}
+		response.Header.Set("Retry-After", "3")
+
+		pkg.Expect(t, 3*time.Second, policy.Delay(1, response))
+
+		response.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+		wait, ok := RetryAfter(response)
+
+		pkg.Expect(t, true, ok)
+		pkg.Expect(t, time.Duration(0), wait)
+
+		get, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1", nil)
+		post, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1", nil)
+		response = &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}
+
+		_, retry := policy.ShouldRetry(get, 1, response, nil)
+		pkg.Expect(t, true, retry)
+
+		_, retry = policy.ShouldRetry(get, 3, response, nil)
+		pkg.Expect(t, false, retry)
+
+		_, retry = policy.ShouldRetry(post, 1, response, nil)
+		pkg.Expect(t, false, retry)
+
+		post.Header.Set("Idempotency-Key", "123")
+		_, retry = policy.ShouldRetry(post, 1, response, nil)
+		pkg.Expect(t, true, retry)
+
+		// Waits longer than the max backoff are not retried
+		response.Header.Set("Retry-After", "3600")
+		_, retry = policy.ShouldRetry(get, 1, response, nil)
+		pkg.Expect(t, false, retry)
+
+		response.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		_, retry = policy.ShouldRetry(get, 1, response, nil)
+		pkg.Expect(t, false, retry)
+
+		response.Header.Del("Retry-After")
+
+		response.StatusCode = http.StatusInternalServerError
+		_, retry = policy.ShouldRetry(get, 1, response, nil)
+		pkg.Expect(t, false, retry)
+
+		var nilPolicy *RetryPolicy
+		_, retry = nilPolicy.ShouldRetry(get, 1, response, nil)
+		pkg.Expect(t, false, retry)
+	})
+}
+
+// TestHttpRetry test cases
+func TestHttpRetry(t *testing.T) {
+	t.Run("TestHttpRetry", func(t *testing.T) {
+		var calls int32
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			w.Write(body)
+		}))
+		defer srv.Close()
+
+		attempts := []RetryAttempt{}
+		httpClient := NewHTTPClient()
+		httpClient.Retry = NewRetryPolicy()
+		httpClient.OnRetry = func(attempt RetryAttempt) {
+			attempts = append(attempts, attempt)
+		}
+
+		response, err := httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{})
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, http.StatusOK, response.StatusCode)
+		pkg.Expect(t, int32(3), atomic.LoadInt32(&calls))
+		pkg.Expect(t, 2, len(attempts))
+		pkg.Expect(t, http.StatusServiceUnavailable, attempts[0].StatusCode)
+
+		// Non idempotent methods are not retried by default
+		atomic.StoreInt32(&calls, 0)
+
+		response, err = httpClient.Post(context.TODO(), srv.URL, `{"name":"poodle"}`, map[string]string{}, map[string]string{})
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, http.StatusServiceUnavailable, response.StatusCode)
+		pkg.Expect(t, int32(1), atomic.LoadInt32(&calls))
+
+		// Unless allowed, and the body is sent again
+		atomic.StoreInt32(&calls, 0)
+		httpClient.Retry.NonIdempotent = true
+
+		response, err = httpClient.Post(context.TODO(), srv.URL, `{"name":"poodle"}`, map[string]string{}, map[string]string{})
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, http.StatusOK, response.StatusCode)
+
+		body, _ := httpClient.ToString(response)
+
+		pkg.Expect(t, `{"name":"poodle"}`, body)
+		pkg.Expect(t, int32(3), atomic.LoadInt32(&calls))
+	})
+}
@@ -0,0 +1,453 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeyringService is the service name of the secrets in the OS keyring
+const KeyringService = "poodle"
+
+// GithubSecretKey is the key of the github token in the secret store
+const GithubSecretKey = "poodle/github"
+
+// SecretsPassphraseEnv is the environment variable of the encrypted file store passphrase
+const SecretsPassphraseEnv = "POODLE_SECRETS_PASSPHRASE"
+
+// ErrSecretNotFound is returned if a secret is not stored
+var ErrSecretNotFound = errors.New("Secret not found")
+
+// SecretStore stores secrets by key
+type SecretStore interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// Keyring stores secrets in the OS keyring, macOS keychain or the Secret Service
+// on Linux, and falls back to the encrypted file store if it is not available
+type Keyring struct {
+	Fallback SecretStore
+}
+
+// FileStore stores secrets in an AES-GCM encrypted file
+//
+// The key is derived from the passphrase if set, otherwise a random key is kept
+// in a separate file readable only by the owner.
+type FileStore struct {
+	Path       string
+	KeyPath    string
+	Passphrase string
+
+	sync.Mutex
+}
+
+// NewKeyring creates an instance of keyring falling back to the store
+func NewKeyring(fallback SecretStore) *Keyring {
+	return &Keyring{Fallback: fallback}
+}
+
+// NewFileStore creates an instance of encrypted file store in a directory
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{
+		Path:       filepath.Join(dir, "secrets.enc"),
+		KeyPath:    filepath.Join(dir, "secrets.key"),
+		Passphrase: os.Getenv(SecretsPassphraseEnv),
+	}
+}
+
+// SecretRef gets a reference to a secret stored in the keyring
+func SecretRef(key string) string {
+	return fmt.Sprintf("{$secret:keyring:%s}", key)
+}
+
+// ReplaceSecrets resolves the secret references in data
+//
+// Keyring references get the secret from the store and command references run
+// the command and take its output. Resolved values are cached by reference.
+func ReplaceSecrets(data string, store SecretStore, cache map[string]string) (string, error) {
+	evaluator := &Evaluator{Secrets: true, Store: store, Cache: cache}
+
+	return evaluator.Render(data)
+}
+
+// ResolveSecret gets the secret of a provider like keyring or cmd
+func ResolveSecret(provider, ref string, store SecretStore) (string, error) {
+	ref = strings.TrimSpace(ref)
+
+	switch provider {
+	case "keyring":
+		if store == nil {
+			return "", fmt.Errorf("Secret store is not configured")
+		}
+
+		return store.Get(ref)
+	case "cmd":
+		return runSecretCommand(ref)
+	}
+
+	return "", fmt.Errorf("Unknown secret provider %s, use keyring or cmd", provider)
+}
+
+// Get gets a secret from the OS keyring or the fallback
+func (k *Keyring) Get(key string) (string, error) {
+	name, args, ok := k.command("get", key)
+
+	if !ok {
+		return k.Fallback.Get(key)
+	}
+
+	output, err := exec.Command(name, args...).Output()
+
+	if err != nil {
+		// The secret may have been stored before the keyring was available
+		if value, ferr := k.Fallback.Get(key); ferr == nil {
+			return value, nil
+		}
+
+		return "", ErrSecretNotFound
+	}
+
+	return strings.TrimSuffix(string(output), "\n"), nil
+}
+
+// Set stores a secret in the OS keyring or the fallback
+func (k *Keyring) Set(key, value string) error {
+	name, args, ok := k.command("set", key)
+
+	if !ok {
+		return k.Fallback.Set(key, value)
+	}
+
+	command := exec.Command(name, args...)
+	command.Stdin = strings.NewReader(value)
+
+	// security reads the password from its arguments only, the command is sent on
+	// stdin in interactive mode to keep the secret out of the process list
+	if runtime.GOOS == "darwin" {
+		command = exec.Command(name, "-i")
+		command.Stdin = strings.NewReader(securityLine(append(args, hex.EncodeToString([]byte(value)))))
+	}
+
+	output, err := command.CombinedOutput()
+
+	if err != nil {
+		return fmt.Errorf("Unable to store secret in keyring: %s", strings.TrimSpace(string(output)))
+	}
+
+	// The interactive mode doesn't report failures in its exit status
+	if runtime.GOOS == "darwin" {
+		if stored, err := k.Get(key); err != nil || stored != value {
+			return fmt.Errorf("Unable to store secret in keyring: %s", strings.TrimSpace(string(output)))
+		}
+	}
+
+	return nil
+}
+
+// securityLine formats the arguments as a command line of the security interactive mode
+func securityLine(args []string) string {
+	items := []string{}
+
+	for _, arg := range args {
+		arg = strings.ReplaceAll(arg, `\`, `\\`)
+		arg = strings.ReplaceAll(arg, `"`, `\"`)
+		items = append(items, fmt.Sprintf(`"%s"`, arg))
+	}
+
+	return fmt.Sprintf("%s\n", strings.Join(items, " "))
+}
+
+// Delete deletes a secret from the OS keyring and the fallback
+func (k *Keyring) Delete(key string) error {
+	name, args, ok := k.command("rm", key)
+
+	if !ok {
+		return k.Fallback.Delete(key)
+	}
+
+	keyringErr := exec.Command(name, args...).Run()
+	fallbackErr := k.Fallback.Delete(key)
+
+	if keyringErr != nil && fallbackErr != nil {
+		return ErrSecretNotFound
+	}
+
+	return nil
+}
+
+// command gets the OS keyring command of an operation if available
+func (k *Keyring) command(operation, key string) (string, []string, bool) {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err != nil {
+			return "", nil, false
+		}
+
+		base := []string{"-s", KeyringService, "-a", key}
+
+		switch operation {
+		case "get":
+			return "security", append(append([]string{"find-generic-password"}, base...), "-w"), true
+		case "set":
+			return "security", append(append([]string{"add-generic-password", "-U"}, base...), "-X"), true
+		default:
+			return "security", append([]string{"delete-generic-password"}, base...), true
+		}
+	case "linux":
+		// Headless machines have no session bus to reach the Secret Service
+		if _, err := exec.LookPath("secret-tool"); err != nil || os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+			return "", nil, false
+		}
+
+		base := []string{"service", KeyringService, "account", key}
+
+		switch operation {
+		case "get":
+			return "secret-tool", append([]string{"lookup"}, base...), true
+		case "set":
+			return "secret-tool", append([]string{"store", fmt.Sprintf("--label=%s %s", KeyringService, key)}, base...), true
+		default:
+			return "secret-tool", append([]string{"clear"}, base...), true
+		}
+	}
+
+	return "", nil, false
+}
+
+// Get gets a secret from the file
+func (f *FileStore) Get(key string) (string, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	secrets, err := f.load()
+
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := secrets[key]
+
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+
+	return value, nil
+}
+
+// Set stores a secret in the file
+func (f *FileStore) Set(key, value string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	secrets, err := f.load()
+
+	if err != nil {
+		return err
+	}
+
+	secrets[key] = value
+
+	return f.store(secrets)
+}
+
+// Delete deletes a secret from the file
+func (f *FileStore) Delete(key string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	secrets, err := f.load()
+
+	if err != nil {
+		return err
+	}
+
+	if _, ok := secrets[key]; !ok {
+		return ErrSecretNotFound
+	}
+
+	delete(secrets, key)
+
+	return f.store(secrets)
+}
+
+// load decrypts the secrets file, a missing file has no secrets
+//
+// The file is the salt, the nonce then the sealed JSON of the secrets.
+func (f *FileStore) load() (map[string]string, error) {
+	secrets := make(map[string]string)
+	data, err := ioutil.ReadFile(f.Path)
+
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 16 {
+		return nil, fmt.Errorf("Invalid secrets file %s", f.Path)
+	}
+
+	gcm, err := f.cipher(data[:16], false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[16:]
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("Invalid secrets file %s", f.Path)
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decrypt secrets file %s, check the key or %s", f.Path, SecretsPassphraseEnv)
+	}
+
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("Invalid secrets file %s: %s", f.Path, err.Error())
+	}
+
+	return secrets, nil
+}
+
+// store encrypts the secrets into the file with a new salt and nonce
+func (f *FileStore) store(secrets map[string]string) error {
+	plain, err := json.Marshal(secrets)
+
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, 16)
+
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+
+	gcm, err := f.cipher(salt, true)
+
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	data := bytes.Join([][]byte{salt, nonce, gcm.Seal(nil, nonce, plain, nil)}, nil)
+
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(f.Path, data, 0600)
+}
+
+// cipher creates the AES-GCM cipher from the passphrase or the key file
+func (f *FileStore) cipher(salt []byte, create bool) (cipher.AEAD, error) {
+	var key []byte
+	var err error
+
+	if f.Passphrase != "" {
+		key, err = scrypt.Key([]byte(f.Passphrase), salt, 1<<15, 8, 1, 32)
+	} else {
+		key, err = f.key(create)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// key reads the random key from its file, it is created if missing
+func (f *FileStore) key(create bool) ([]byte, error) {
+	key, err := ioutil.ReadFile(f.KeyPath)
+
+	if err == nil && len(key) == 32 {
+		return key, nil
+	}
+
+	if err == nil || !os.IsNotExist(err) || !create {
+		return nil, fmt.Errorf("Invalid secrets key file %s", f.KeyPath)
+	}
+
+	key = make([]byte, 32)
+
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.KeyPath), 0700); err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(f.KeyPath, key, 0600); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// runSecretCommand runs a command through the shell and gets its output
+func runSecretCommand(command string) (string, error) {
+	if command == "" {
+		return "", fmt.Errorf("Secret command is empty")
+	}
+
+	name, flag := "sh", "-c"
+
+	if runtime.GOOS == "windows" {
+		name, flag = "cmd", "/C"
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(name, flag, command)
+	cmd.Stderr = stderr
+
+	output, err := cmd.Output()
+
+	if err != nil {
+		if stderr.Len() > 0 {
+			return "", fmt.Errorf("Secret command failed: %s", strings.TrimSpace(stderr.String()))
+		}
+
+		return "", fmt.Errorf("Secret command failed: %s", err.Error())
+	}
+
+	return strings.TrimRight(string(output), "\r\n"), nil
+}
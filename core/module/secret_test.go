@@ -0,0 +1,135 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/pkg"
+)
+
+// TestSecret test cases
+func TestSecret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poodle")
+	pkg.Expect(t, nil, err)
+	defer os.RemoveAll(dir)
+
+	t.Run("TestFileStore", func(t *testing.T) {
+		store := NewFileStore(filepath.Join(dir, "key"))
+		store.Passphrase = ""
+
+		_, err := store.Get("poodle/github")
+		pkg.Expect(t, ErrSecretNotFound, err)
+
+		pkg.Expect(t, nil, store.Set("poodle/github", "t0ken"))
+		pkg.Expect(t, nil, store.Set("api/key", "k3y"))
+
+		value, err := store.Get("poodle/github")
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "t0ken", value)
+
+		data, err := ioutil.ReadFile(store.Path)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, false, strings.Contains(string(data), "t0ken"))
+
+		info, err := os.Stat(store.KeyPath)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, os.FileMode(0600), info.Mode().Perm())
+
+		pkg.Expect(t, nil, store.Delete("api/key"))
+		pkg.Expect(t, ErrSecretNotFound, store.Delete("api/key"))
+
+		_, err = store.Get("api/key")
+		pkg.Expect(t, ErrSecretNotFound, err)
+	})
+
+	t.Run("TestFileStorePassphrase", func(t *testing.T) {
+		store := NewFileStore(filepath.Join(dir, "passphrase"))
+		store.Passphrase = "correct horse"
+
+		pkg.Expect(t, nil, store.Set("api/key", "k3y"))
+
+		value, err := store.Get("api/key")
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "k3y", value)
+
+		_, err = os.Stat(store.KeyPath)
+		pkg.Expect(t, true, os.IsNotExist(err))
+
+		store.Passphrase = "wrong"
+
+		_, err = store.Get("api/key")
+		pkg.Expect(t, true, strings.HasPrefix(err.Error(), "Unable to decrypt secrets file"))
+	})
+
+	t.Run("TestSecurityLine", func(t *testing.T) {
+		pkg.Expect(
+			t,
+			`"add-generic-password" "-a" "team \"api\" \\key" "-X" "736563726574"`+"\n",
+			securityLine([]string{"add-generic-password", "-a", `team "api" \key`, "-X", "736563726574"}),
+		)
+	})
+
+	t.Run("TestReplaceSecrets", func(t *testing.T) {
+		store := NewFileStore(filepath.Join(dir, "replace"))
+		store.Passphrase = ""
+		pkg.Expect(t, nil, store.Set("poodle/github", "t0ken"))
+
+		tokens, err := Tokenize("token " + SecretRef("poodle/github"))
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 2, len(tokens))
+		pkg.Expect(t, SecretRefToken, tokens[1].Kind)
+		pkg.Expect(t, "keyring", tokens[1].Name)
+		pkg.Expect(t, "poodle/github", tokens[1].Args[0][0].Text)
+
+		cache := map[string]string{}
+
+		value, err := ReplaceSecrets("token {$secret:keyring:poodle/github} {$secret:cmd:printf 'k3y\n'}", store, cache)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "token t0ken k3y", value)
+		pkg.Expect(t, 2, len(cache))
+
+		value, err = ReplaceSecrets("{$secret:vault:api/key}", store, nil)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "{$secret:vault:api/key}", value)
+
+		_, err = ReplaceSecrets("{$secret:keyring:missing}", store, nil)
+		pkg.Expect(t, "Error in {$secret:keyring:missing}: Secret not found", err.Error())
+
+		_, err = ReplaceSecrets("{$secret:cmd:echo failed >&2; exit 1}", store, nil)
+		pkg.Expect(t, "Error in {$secret:cmd:echo failed >&2; exit 1}: Secret command failed: failed", err.Error())
+
+		_, err = ReplaceSecrets("{$secret:keyring:poodle/github}", nil, nil)
+		pkg.Expect(t, "Error in {$secret:keyring:poodle/github}: Secret store is not configured", err.Error())
+	})
+
+	t.Run("TestCallerSecrets", func(t *testing.T) {
+		store := NewFileStore(filepath.Join(dir, "caller"))
+		store.Passphrase = ""
+		pkg.Expect(t, nil, store.Set("api/key", "k3y"))
+
+		service := &model.Service{}
+		service.Main.ID = "secrets"
+		service.Main.ServiceURL = "http://127.0.0.1"
+		service.Security.Scheme = "api_key"
+		service.Security.APIKey.Header = []string{"X-API-KEY", "{$secret:keyring:api/key}"}
+		service.Endpoint = []model.Endpoint{{ID: "list", Method: "get", URI: "/items"}}
+
+		caller := NewCaller(NewHTTPClient())
+		caller.Secrets = NewKeyring(store)
+
+		pkg.Expect(t, 0, len(caller.GetFields("secrets - list", service)))
+
+		_, _, headers, err := caller.Prepare(service, service.Endpoint[0], map[string]Field{})
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "k3y", headers["X-API-KEY"])
+		pkg.Expect(t, "key=[REDACTED]", caller.Redact("key=k3y"))
+	})
+}
@@ -0,0 +1,188 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/logrusorgru/aurora/v3"
+)
+
+// EventStreamType is the content type of server sent events
+const EventStreamType = "text/event-stream"
+
+// NDJSONType is the content type of newline delimited JSON
+const NDJSONType = "application/x-ndjson"
+
+// MaxStreamLine is the max size of a line in a stream
+const MaxStreamLine = 1 << 20
+
+// StreamEvent struct
+type StreamEvent struct {
+	Time  time.Time
+	Event string
+	ID    string
+	Data  string
+	Retry time.Duration
+}
+
+// IsStream checks if the response is a server sent events or a newline delimited JSON stream
+func IsStream(response *http.Response) bool {
+	return IsEventStream(response) || mediaType(response) == NDJSONType
+}
+
+// IsEventStream checks if the response is a server sent events stream
+func IsEventStream(response *http.Response) bool {
+	return mediaType(response) == EventStreamType
+}
+
+// IsTimeout checks if an error is caused by a timeout
+func IsTimeout(err error) bool {
+	var netErr net.Error
+
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// Stream reads the response body incrementally and calls the handler for each event
+//
+// Server sent events are parsed into their fields, any other body is read line by
+// line with each non empty line as an event. It stops once the body ends, the handler
+// fails or max events are received if max is positive, and returns the number of events.
+func (h *HTTPClient) Stream(response *http.Response, max int, handler func(StreamEvent) error) (int, error) {
+	defer response.Body.Close()
+
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), MaxStreamLine)
+
+	count := 0
+	sse := IsEventStream(response)
+	event := StreamEvent{}
+	data := []string{}
+	hasData := false
+
+	dispatch := func(item StreamEvent) (bool, error) {
+		item.Time = time.Now()
+		count++
+
+		if err := handler(item); err != nil {
+			return true, err
+		}
+
+		return max > 0 && count >= max, nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if !sse {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			if done, err := dispatch(StreamEvent{Data: line}); done || err != nil {
+				return count, err
+			}
+
+			continue
+		}
+
+		// A blank line dispatches the event, the id and retry fields apply without data
+		if line == "" {
+			if hasData {
+				event.Data = strings.Join(data, "\n")
+
+				if done, err := dispatch(event); done || err != nil {
+					return count, err
+				}
+			}
+
+			event = StreamEvent{ID: event.ID}
+			data = []string{}
+			hasData = false
+			continue
+		}
+
+		// Comments are used as keep alive
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				event.ID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				event.Retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// Pretty returns the event prefixed with its time and fields
+func (e StreamEvent) Pretty() string {
+	value := Blue(e.Time.Format("15:04:05.000")).String()
+
+	for _, item := range [][]string{
+		{"event", e.Event},
+		{"id", e.ID},
+	} {
+		if item[1] != "" {
+			value = value + fmt.Sprintf(" %s=%s", Cyan(item[0]), item[1])
+		}
+	}
+
+	if e.Retry > 0 {
+		value = value + fmt.Sprintf(" %s=%s", Cyan("retry"), e.Retry)
+	}
+
+	if strings.Contains(e.Data, "\n") {
+		return value + "\n" + Yellow(e.Data).String()
+	}
+
+	return value + " " + Yellow(e.Data).String()
+}
+
+// mediaType gets the media type of the response
+func mediaType(response *http.Response) string {
+	value, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(value)
+}
@@ -0,0 +1,219 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/pkg"
+)
+
+// streamServer writes the chunks flushing each one and keeps the stream open until the test ends
+func streamServer(contentType string, chunks []string, done <-chan struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+
+		for _, chunk := range chunks {
+			fmt.Fprint(w, chunk)
+			w.(http.Flusher).Flush()
+		}
+
+		if done != nil {
+			select {
+			case <-done:
+			case <-r.Context().Done():
+			}
+		}
+	}))
+}
+
+// TestStream test cases
+func TestStream(t *testing.T) {
+	t.Run("TestStreamEvents", func(t *testing.T) {
+		srv := streamServer("text/event-stream; charset=utf-8", []string{
+			": keep alive\n\n",
+			"event: created\nid: 1\ndata: {\"id\":1}\n\n",
+			"data: first line\r\ndata: second line\r\n\r\n",
+			"id: 3\nretry: 3000\ndata:no space\n\n",
+			"event: ignored\n",
+		}, nil)
+		defer srv.Close()
+
+		httpClient := NewHTTPClient()
+
+		response, err := httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, true, IsStream(response))
+		pkg.Expect(t, true, IsEventStream(response))
+
+		events := []StreamEvent{}
+
+		count, err := httpClient.Stream(response, 0, func(event StreamEvent) error {
+			events = append(events, event)
+			return nil
+		})
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 3, count)
+
+		pkg.Expect(t, "created", events[0].Event)
+		pkg.Expect(t, "1", events[0].ID)
+		pkg.Expect(t, `{"id":1}`, events[0].Data)
+
+		pkg.Expect(t, "", events[1].Event)
+		pkg.Expect(t, "1", events[1].ID)
+		pkg.Expect(t, "first line\nsecond line", events[1].Data)
+
+		pkg.Expect(t, "3", events[2].ID)
+		pkg.Expect(t, 3*time.Second, events[2].Retry)
+		pkg.Expect(t, "no space", events[2].Data)
+		pkg.Expect(t, false, events[2].Time.IsZero())
+	})
+
+	t.Run("TestStreamMaxEvents", func(t *testing.T) {
+		done := make(chan struct{})
+		defer close(done)
+
+		srv := streamServer(NDJSONType, []string{"{\"n\":1}\n", "\n{\"n\":2}\n", "{\"n\":3}\n"}, done)
+		defer srv.Close()
+
+		httpClient := NewHTTPClient()
+		httpClient.Dumper = NewDumper(&bytes.Buffer{}, DefaultRedactHeaders, true)
+
+		response, err := httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, true, IsStream(response))
+		pkg.Expect(t, false, IsEventStream(response))
+
+		data := []string{}
+
+		count, err := httpClient.Stream(response, 2, func(event StreamEvent) error {
+			data = append(data, event.Data)
+			return nil
+		})
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 2, count)
+		pkg.Expect(t, []string{`{"n":1}`, `{"n":2}`}, data)
+	})
+
+	t.Run("TestStreamTimeout", func(t *testing.T) {
+		done := make(chan struct{})
+		defer close(done)
+
+		srv := streamServer(EventStreamType, []string{"data: hello\n\n"}, done)
+		defer srv.Close()
+
+		httpClient := NewHTTPClient()
+		httpClient.Timeout = 200 * time.Millisecond
+
+		response, err := httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+
+		count, err := httpClient.Stream(response, 0, func(event StreamEvent) error {
+			return nil
+		})
+
+		pkg.Expect(t, 1, count)
+		pkg.Expect(t, true, IsTimeout(err))
+	})
+
+	t.Run("TestStreamCancel", func(t *testing.T) {
+		done := make(chan struct{})
+		defer close(done)
+
+		srv := streamServer(EventStreamType, []string{"data: hello\n\n"}, done)
+		defer srv.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		httpClient := NewHTTPClient()
+
+		response, err := httpClient.Get(ctx, srv.URL, map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+
+		count, err := httpClient.Stream(response, 0, func(event StreamEvent) error {
+			cancel()
+			return nil
+		})
+
+		pkg.Expect(t, 1, count)
+		pkg.Expect(t, true, err != nil)
+		pkg.Expect(t, context.Canceled, ctx.Err())
+	})
+
+	t.Run("TestStreamEndpointTimeout", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", EventStreamType)
+			w.WriteHeader(http.StatusOK)
+
+			for i := 1; i <= 3; i++ {
+				fmt.Fprintf(w, "data: %d\n\n", i)
+				w.(http.Flusher).Flush()
+				time.Sleep(200 * time.Millisecond)
+			}
+		}))
+		defer srv.Close()
+
+		service := model.NewEmptyService("stream")
+		service.Main.ID = "stream"
+		service.Main.ServiceURL = srv.URL
+		service.Main.Timeout = "300ms"
+		service.Endpoint = []model.Endpoint{{ID: "Events", Method: "get", URI: "/", Public: true, Stream: true}}
+
+		caller := NewCaller(NewHTTPClient())
+
+		// The service timeout only bounds waiting for the response headers
+		pkg.Expect(t, nil, caller.SetTimeouts(service, service.Endpoint[0]))
+		pkg.Expect(t, time.Duration(0), caller.HTTPClient.Timeout)
+		pkg.Expect(t, 300*time.Millisecond, caller.HTTPClient.ReadTimeout)
+
+		// The endpoint timeout overrides the service read timeout
+		other := *service
+		other.Main.ReadTimeout = "5s"
+		other.Endpoint = []model.Endpoint{{ID: "Events", Stream: true, Timeout: "100ms"}}
+
+		pkg.Expect(t, nil, caller.SetTimeouts(&other, other.Endpoint[0]))
+		pkg.Expect(t, time.Duration(0), caller.HTTPClient.Timeout)
+		pkg.Expect(t, 100*time.Millisecond, caller.HTTPClient.ReadTimeout)
+
+		other.Endpoint[0].ReadTimeout = "1s"
+
+		pkg.Expect(t, nil, caller.SetTimeouts(&other, other.Endpoint[0]))
+		pkg.Expect(t, time.Second, caller.HTTPClient.ReadTimeout)
+
+		pkg.Expect(t, nil, caller.SetTimeouts(service, service.Endpoint[0]))
+
+		response, err := caller.CallWithContext(context.TODO(), "stream - Events", service, map[string]Field{})
+		pkg.Expect(t, nil, err)
+
+		count, err := caller.HTTPClient.Stream(response, 0, func(event StreamEvent) error {
+			return nil
+		})
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 3, count)
+
+		// The timeout flag ends the stream
+		caller.Timeout = 300 * time.Millisecond
+
+		response, err = caller.CallWithContext(context.TODO(), "stream - Events", service, map[string]Field{})
+		pkg.Expect(t, nil, err)
+
+		count, err = caller.HTTPClient.Stream(response, 0, func(event StreamEvent) error {
+			return nil
+		})
+
+		pkg.Expect(t, 2, count)
+		pkg.Expect(t, true, IsTimeout(err))
+	})
+}
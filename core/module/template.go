@@ -0,0 +1,434 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/clivern/poodle/core/model"
+)
+
+// TokenKind is the kind of a template token
+type TokenKind int
+
+const (
+	// TextToken is a literal text
+	TextToken TokenKind = iota
+	// VarToken is a variable like {$name}, {$name:default} or {$!secret}
+	VarToken
+	// FunctionToken is a function call like {$now("RFC3339")}
+	FunctionToken
+	// SecretRefToken is a secret reference like {$secret:keyring:poodle/github}
+	SecretRefToken
+)
+
+// Token is a literal text or a placeholder of a template
+type Token struct {
+	Kind TokenKind
+	// Raw is the placeholder as written
+	Raw string
+	// Text is the text of a literal with the escapes removed
+	Text string
+	// Name is the variable name, the function name or the secret provider
+	Name     string
+	Default  string
+	Optional bool
+	Secret   bool
+	// Args are the parsed function arguments or the secret reference
+	Args [][]Token
+	// Line and Column are the position of the placeholder, starting from 1
+	Line   int
+	Column int
+}
+
+// TemplateError is a template syntax error at a line and column of the template
+type TemplateError struct {
+	Message string
+	Line    int
+	Column  int
+}
+
+// Error returns the error message with its position
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("%s at line %d, column %d", e.Message, e.Line, e.Column)
+}
+
+// Evaluator evaluates templates
+//
+// Placeholders which are not evaluated are kept as written.
+type Evaluator struct {
+	// Fields are the values of the variables, missing variables are kept
+	Fields map[string]Field
+	// Functions enables the evaluation of functions
+	Functions bool
+	// Service resolves the relative paths of functions like {$file("body.json")} if set
+	Service *model.Service
+	// Secrets enables the resolution of secret references from the store,
+	// resolved values are cached by reference if the cache is set
+	Secrets bool
+	Store   SecretStore
+	Cache   map[string]string
+}
+
+var (
+	// secretRefPrefix matches the start of a secret reference like secret:keyring:,
+	// other values like {$secret:abc:def} are a variable named secret with a default
+	secretRefPrefix = regexp.MustCompile(`^secret:(keyring|cmd):`)
+
+	// functionPrefix matches the start of a function call like now(
+	functionPrefix = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\(`)
+
+	// varName matches a valid variable name
+	varName = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+)
+
+// Tokenize parses a template into tokens
+//
+// A placeholder starts with {$ and ends with its matching brace, so defaults may
+// contain braces and colons like {$meta:{"a": 1}} or {$url:http://127.0.0.1:8080}.
+// A literal {$ is written as \{$.
+func Tokenize(data string) ([]Token, error) {
+	return tokenize(data, data, 0)
+}
+
+// Execute evaluates the tokens of a template
+func (e *Evaluator) Execute(tokens []Token) (string, error) {
+	var b strings.Builder
+
+	for _, token := range tokens {
+		switch token.Kind {
+		case TextToken:
+			b.WriteString(token.Text)
+		case VarToken:
+			field, ok := e.Fields[token.Name]
+
+			switch {
+			case !ok:
+				b.WriteString(token.Raw)
+			case field.Value == "" && token.Optional:
+				b.WriteString(token.Default)
+			default:
+				b.WriteString(field.Value)
+			}
+		case FunctionToken:
+			if !e.Functions {
+				b.WriteString(token.Raw)
+				continue
+			}
+
+			args := []string{}
+
+			for _, arg := range token.Args {
+				value, err := e.Execute(arg)
+
+				if err != nil {
+					return "", err
+				}
+
+				args = append(args, value)
+			}
+
+			if function, ok := Functions[token.Name]; ok && function.Path && e.Service != nil && len(args) > 0 {
+				args[0] = relativePath(e.Service, args[0])
+			}
+
+			value, err := CallFunction(token.Name, args)
+
+			if err != nil {
+				return "", fmt.Errorf("Error in %s: %s", token.Raw, err.Error())
+			}
+
+			b.WriteString(value)
+		case SecretRefToken:
+			if !e.Secrets {
+				b.WriteString(token.Raw)
+				continue
+			}
+
+			ref, err := e.Execute(token.Args[0])
+
+			if err != nil {
+				return "", err
+			}
+
+			key := fmt.Sprintf("{$secret:%s:%s}", token.Name, ref)
+
+			if value, ok := e.Cache[key]; ok {
+				b.WriteString(value)
+				continue
+			}
+
+			value, err := ResolveSecret(token.Name, ref, e.Store)
+
+			if err != nil {
+				return "", fmt.Errorf("Error in %s: %s", token.Raw, err.Error())
+			}
+
+			if e.Cache != nil {
+				e.Cache[key] = value
+			}
+
+			b.WriteString(value)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// Render parses and evaluates a template
+func (e *Evaluator) Render(data string) (string, error) {
+	tokens, err := Tokenize(data)
+
+	if err != nil {
+		return "", err
+	}
+
+	return e.Execute(tokens)
+}
+
+// IsPlaceholder checks if the data is a single placeholder of a kind
+func IsPlaceholder(data string, kind TokenKind) bool {
+	tokens, err := Tokenize(data)
+
+	return err == nil && len(tokens) == 1 && tokens[0].Kind == kind
+}
+
+// tokenize parses a part of the source starting at an offset
+func tokenize(source, data string, offset int) ([]Token, error) {
+	tokens := []Token{}
+	text := strings.Builder{}
+
+	flush := func() {
+		if text.Len() > 0 {
+			tokens = append(tokens, Token{Kind: TextToken, Raw: text.String(), Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(data); {
+		if strings.HasPrefix(data[i:], `\{$`) {
+			text.WriteString("{$")
+			i += 3
+			continue
+		}
+
+		if !strings.HasPrefix(data[i:], "{$") {
+			text.WriteByte(data[i])
+			i++
+			continue
+		}
+
+		end := closingBrace(data, i)
+
+		if end < 0 {
+			line, column := position(source, offset+i)
+
+			return nil, &TemplateError{Message: "Unterminated placeholder", Line: line, Column: column}
+		}
+
+		flush()
+
+		token, err := parsePlaceholder(source, data[i:end+1], offset+i)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+		i = end + 1
+	}
+
+	flush()
+
+	return tokens, nil
+}
+
+// parsePlaceholder parses a placeholder at an offset of the source
+func parsePlaceholder(source, raw string, offset int) (Token, error) {
+	line, column := position(source, offset)
+	content := raw[2 : len(raw)-1]
+	token := Token{Raw: raw, Line: line, Column: column}
+
+	if match := secretRefPrefix.FindStringSubmatch(content); match != nil {
+		ref, err := tokenize(source, content[len(match[0]):], offset+2+len(match[0]))
+
+		if err != nil {
+			return token, err
+		}
+
+		token.Kind = SecretRefToken
+		token.Name = match[1]
+		token.Args = [][]Token{ref}
+
+		return token, nil
+	}
+
+	if match := functionPrefix.FindStringSubmatch(content); match != nil && strings.HasSuffix(content, ")") {
+		token.Kind = FunctionToken
+		token.Name = match[1]
+
+		start := offset + 2 + len(match[0])
+		raw := content[len(match[0]) : len(content)-1]
+
+		for _, arg := range splitArgs(raw, Functions[token.Name].Args == 1) {
+			item := strings.TrimSpace(raw[arg[0]:arg[1]])
+			at := start + arg[0] + strings.Index(raw[arg[0]:arg[1]], item)
+
+			if len(item) >= 2 && strings.HasPrefix(item, `"`) && strings.HasSuffix(item, `"`) {
+				value, err := strconv.Unquote(item)
+
+				if err != nil {
+					value = item[1 : len(item)-1]
+				}
+
+				item = value
+				at++
+			}
+
+			tokens, err := tokenize(source, item, at)
+
+			if err != nil {
+				return token, err
+			}
+
+			token.Args = append(token.Args, tokens)
+		}
+
+		return token, nil
+	}
+
+	token.Kind = VarToken
+
+	// Secrets like {$!token} are masked at the prompt and redacted from the output
+	if strings.HasPrefix(content, "!") {
+		token.Secret = true
+		content = content[1:]
+	}
+
+	token.Name = content
+
+	if i := strings.Index(content, ":"); i >= 0 {
+		token.Name = content[:i]
+		token.Default = strings.Replace(content[i+1:], `\{$`, "{$", -1)
+		token.Optional = true
+	}
+
+	if token.Name == "" {
+		return token, &TemplateError{Message: "Empty placeholder name", Line: line, Column: column}
+	}
+
+	if !varName.MatchString(token.Name) {
+		return token, &TemplateError{
+			Message: fmt.Sprintf("Invalid placeholder name %s, use letters, digits, _, . or -", strconv.Quote(token.Name)),
+			Line:    line,
+			Column:  column,
+		}
+	}
+
+	return token, nil
+}
+
+// closingBrace gets the index of the brace closing the placeholder at start
+//
+// Braces are counted outside of the double quoted function arguments, quotes in
+// variable defaults are taken as is. -1 is returned if the placeholder is not closed.
+func closingBrace(data string, start int) int {
+	depth := 0
+	quoted := false
+	call := functionPrefix.MatchString(data[start+2:])
+	parens := 0
+	// A quote or a new line in a variable name means the placeholder was left open like "{$id"
+	named := !call
+
+	for i := start; i < len(data); i++ {
+		c := data[i]
+
+		if named {
+			switch c {
+			case ':', '}':
+				named = false
+			case '"', '\n':
+				return -1
+			}
+		}
+
+		if quoted {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				quoted = false
+			}
+
+			continue
+		}
+
+		switch c {
+		case '"':
+			quoted = call && parens > 0
+		case '(':
+			parens++
+		case ')':
+			parens--
+		case '{':
+			depth++
+		case '}':
+			depth--
+
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// splitArgs gets the bounds of the function arguments split on commas outside
+// of quotes and placeholders, a single argument is taken as is
+func splitArgs(raw string, single bool) [][2]int {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+
+	if single {
+		return [][2]int{{0, len(raw)}}
+	}
+
+	items := [][2]int{}
+	start := 0
+	depth := 0
+	quoted := false
+
+	for i := 0; i < len(raw); i++ {
+		switch {
+		case raw[i] == '\\' && quoted:
+			i++
+		case raw[i] == '"':
+			quoted = !quoted
+		case raw[i] == '{' && !quoted:
+			depth++
+		case raw[i] == '}' && !quoted:
+			depth--
+		case raw[i] == ',' && !quoted && depth == 0:
+			items = append(items, [2]int{start, i})
+			start = i + 1
+		}
+	}
+
+	return append(items, [2]int{start, len(raw)})
+}
+
+// position gets the line and column of an offset of the source
+func position(source string, offset int) (int, int) {
+	before := source[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := offset - strings.LastIndex(before, "\n")
+
+	return line, column
+}
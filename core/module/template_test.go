@@ -0,0 +1,130 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"testing"
+
+	"github.com/clivern/poodle/pkg"
+)
+
+// TestTemplate test cases
+func TestTemplate(t *testing.T) {
+	t.Run("TestTokenize", func(t *testing.T) {
+		tokens, err := Tokenize(`{$serviceURL:http://127.0.0.1:8080}/item/{$!token}`)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 3, len(tokens))
+		pkg.Expect(t, VarToken, tokens[0].Kind)
+		pkg.Expect(t, "serviceURL", tokens[0].Name)
+		pkg.Expect(t, "http://127.0.0.1:8080", tokens[0].Default)
+		pkg.Expect(t, true, tokens[0].Optional)
+		pkg.Expect(t, "/item/", tokens[1].Text)
+		pkg.Expect(t, "token", tokens[2].Name)
+		pkg.Expect(t, true, tokens[2].Secret)
+		pkg.Expect(t, false, tokens[2].Optional)
+		pkg.Expect(t, 1, tokens[2].Line)
+		pkg.Expect(t, 42, tokens[2].Column)
+
+		tokens, err = Tokenize(`{"meta": {$meta:{"tags": ["a"], "n": {"m": 1}}}}`)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 3, len(tokens))
+		pkg.Expect(t, `{"tags": ["a"], "n": {"m": 1}}`, tokens[1].Default)
+		pkg.Expect(t, "}", tokens[2].Text)
+
+		tokens, err = Tokenize(`{$name:He said "hi} {$base64("a}b")}`)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 3, len(tokens))
+		pkg.Expect(t, `He said "hi`, tokens[0].Default)
+		pkg.Expect(t, FunctionToken, tokens[2].Kind)
+		pkg.Expect(t, "a}b", tokens[2].Args[0][0].Text)
+
+		tokens, err = Tokenize(`{$secret:abc:def}`)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, VarToken, tokens[0].Kind)
+		pkg.Expect(t, "secret", tokens[0].Name)
+		pkg.Expect(t, "abc:def", tokens[0].Default)
+
+		tokens, err = Tokenize(`{$base64("{$user}:{$pass}")} {$random_int(1, {$max:10})} {$secret:cmd:pass show {$path}}`)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 5, len(tokens))
+		pkg.Expect(t, FunctionToken, tokens[0].Kind)
+		pkg.Expect(t, 1, len(tokens[0].Args))
+		pkg.Expect(t, 3, len(tokens[0].Args[0]))
+		pkg.Expect(t, 2, len(tokens[2].Args))
+		pkg.Expect(t, "max", tokens[2].Args[1][0].Name)
+		pkg.Expect(t, SecretRefToken, tokens[4].Kind)
+		pkg.Expect(t, "cmd", tokens[4].Name)
+		pkg.Expect(t, "path", tokens[4].Args[0][1].Name)
+	})
+
+	t.Run("TestTokenizeEscape", func(t *testing.T) {
+		tokens, err := Tokenize(`literal \{$name} and {$name:\{$x}}`)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 2, len(tokens))
+		pkg.Expect(t, "literal {$name} and ", tokens[0].Text)
+		pkg.Expect(t, "{$x}", tokens[1].Default)
+	})
+
+	t.Run("TestTokenizeErrors", func(t *testing.T) {
+		_, err := Tokenize("{\n  \"id\": \"{$id\"\n}")
+
+		pkg.Expect(t, "Unterminated placeholder at line 2, column 10", err.Error())
+
+		_, err = Tokenize("a\nb {$}")
+
+		pkg.Expect(t, "Empty placeholder name at line 2, column 3", err.Error())
+
+		_, err = Tokenize(`{$first name}`)
+
+		pkg.Expect(t, `Invalid placeholder name "first name", use letters, digits, _, . or - at line 1, column 1`, err.Error())
+
+		_, err = Tokenize(`{$base64({$})}`)
+
+		pkg.Expect(t, "Empty placeholder name at line 1, column 10", err.Error())
+	})
+
+	t.Run("TestEvaluator", func(t *testing.T) {
+		fields := map[string]Field{
+			"id":   {Value: "{$uuid()}"},
+			"type": {IsOptional: true, Default: "default"},
+			"user": {Value: "joe"},
+		}
+
+		evaluator := &Evaluator{Fields: fields, Functions: true}
+
+		value, err := evaluator.Render(`{$id}/{$type:default}/{$type:other}/{$base64("{$user}:x")}/{$missing}/\{$id}`)
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, "{$uuid()}/default/other/am9lOng=/{$missing}/{$id}", value)
+
+		caller := NewCaller(NewHTTPClient())
+
+		pkg.Expect(t, "joe {$uuid()}", caller.ReplaceVars("{$user} {$uuid()}", fields))
+		pkg.Expect(t, "{$user", caller.ReplaceVars("{$user", fields))
+		pkg.Expect(t, true, IsPlaceholder("{$user}", VarToken))
+		pkg.Expect(t, false, IsPlaceholder("{$user} ", VarToken))
+
+		_, err = caller.Render("{$user", fields)
+
+		pkg.Expect(t, "Unterminated placeholder at line 1, column 1", err.Error())
+	})
+
+	t.Run("TestParseFields", func(t *testing.T) {
+		caller := NewCaller(NewHTTPClient())
+		fields := caller.ParseFields(`{$serviceURL:http://127.0.0.1:8080} {$sha256({$!key})} {$meta:{"a": 1}}`)
+
+		pkg.Expect(t, 3, len(fields))
+		pkg.Expect(t, "http://127.0.0.1:8080", fields["serviceURL"].Default)
+		pkg.Expect(t, true, fields["key"].IsSecret())
+		pkg.Expect(t, false, fields["key"].IsOptional)
+		pkg.Expect(t, `{"a": 1}`, fields["meta"].Default)
+	})
+}
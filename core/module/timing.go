@@ -0,0 +1,257 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	. "github.com/logrusorgru/aurora/v3"
+)
+
+// timingKey is the context key of the request timing
+type timingKey struct{}
+
+// Timing struct
+type Timing struct {
+	Start        time.Time
+	DNSStart     time.Time
+	DNSDone      time.Time
+	ConnectStart time.Time
+	ConnectDone  time.Time
+	TLSStart     time.Time
+	TLSDone      time.Time
+	GotConn      time.Time
+	WroteRequest time.Time
+	FirstByte    time.Time
+	BodyDone     time.Time
+	Reused       bool
+	WasIdle      bool
+	IdleTime     time.Duration
+	RemoteAddr   string
+	LocalAddr    string
+
+	sync.Mutex
+}
+
+// NewTiming creates an instance of timing
+func NewTiming() *Timing {
+	return &Timing{}
+}
+
+// WithTiming attaches a timing tracer to the context
+func WithTiming(ctx context.Context, timing *Timing) context.Context {
+	return context.WithValue(
+		httptrace.WithClientTrace(ctx, timing.Trace()),
+		timingKey{},
+		timing,
+	)
+}
+
+// GetTiming gets the timing of the request that got the response
+func GetTiming(response *http.Response) (*Timing, bool) {
+	if response == nil || response.Request == nil {
+		return nil, false
+	}
+
+	timing, ok := response.Request.Context().Value(timingKey{}).(*Timing)
+
+	return timing, ok
+}
+
+// Trace returns a client trace to record the request phases
+func (t *Timing) Trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(_ httptrace.DNSStartInfo) {
+			t.record(&t.DNSStart)
+		},
+		DNSDone: func(_ httptrace.DNSDoneInfo) {
+			t.record(&t.DNSDone)
+		},
+		ConnectStart: func(_, _ string) {
+			t.record(&t.ConnectStart)
+		},
+		ConnectDone: func(_, _ string, _ error) {
+			t.record(&t.ConnectDone)
+		},
+		TLSHandshakeStart: func() {
+			t.record(&t.TLSStart)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+			t.record(&t.TLSDone)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.Lock()
+			defer t.Unlock()
+
+			t.GotConn = time.Now()
+			t.Reused = info.Reused
+			t.WasIdle = info.WasIdle
+			t.IdleTime = info.IdleTime
+
+			if info.Conn == nil {
+				return
+			}
+
+			if addr := info.Conn.RemoteAddr(); addr != nil {
+				t.RemoteAddr = addr.String()
+			}
+
+			if addr := info.Conn.LocalAddr(); addr != nil {
+				t.LocalAddr = addr.String()
+			}
+		},
+		WroteRequest: func(_ httptrace.WroteRequestInfo) {
+			t.record(&t.WroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			t.record(&t.FirstByte)
+		},
+	}
+}
+
+// Begin marks the start of the request
+func (t *Timing) Begin() {
+	t.record(&t.Start)
+}
+
+// Finish marks the end of the response body if not marked before
+func (t *Timing) Finish() {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.BodyDone.IsZero() {
+		t.BodyDone = time.Now()
+	}
+}
+
+// record sets a timestamp to now
+func (t *Timing) record(item *time.Time) {
+	t.Lock()
+	defer t.Unlock()
+
+	*item = time.Now()
+}
+
+// DNSLookup returns the dns lookup duration
+func (t *Timing) DNSLookup() time.Duration {
+	return between(t.DNSStart, t.DNSDone)
+}
+
+// TCPConnection returns the tcp connect duration
+func (t *Timing) TCPConnection() time.Duration {
+	return between(t.ConnectStart, t.ConnectDone)
+}
+
+// TLSHandshake returns the tls handshake duration
+func (t *Timing) TLSHandshake() time.Duration {
+	return between(t.TLSStart, t.TLSDone)
+}
+
+// ServerProcessing returns the duration between sending the request and the first response byte
+func (t *Timing) ServerProcessing() time.Duration {
+	return between(t.WroteRequest, t.FirstByte)
+}
+
+// TimeToFirstByte returns the duration between the request start and the first response byte
+func (t *Timing) TimeToFirstByte() time.Duration {
+	return between(t.Start, t.FirstByte)
+}
+
+// ContentTransfer returns the duration of reading the response body
+func (t *Timing) ContentTransfer() time.Duration {
+	return between(t.FirstByte, t.BodyDone)
+}
+
+// Total returns the whole request duration
+func (t *Timing) Total() time.Duration {
+	if t.BodyDone.IsZero() {
+		return between(t.Start, t.FirstByte)
+	}
+
+	return between(t.Start, t.BodyDone)
+}
+
+// Pretty returns colored timing breakdown
+func (t *Timing) Pretty() string {
+	t.Lock()
+	defer t.Unlock()
+
+	value := fmt.Sprintf("\n%s\n", Magenta("Timing"))
+
+	for _, item := range []struct {
+		Label    string
+		Duration time.Duration
+	}{
+		{"DNS Lookup", t.DNSLookup()},
+		{"TCP Connection", t.TCPConnection()},
+		{"TLS Handshake", t.TLSHandshake()},
+		{"Server Processing", t.ServerProcessing()},
+		{"Time To First Byte", t.TimeToFirstByte()},
+		{"Content Transfer", t.ContentTransfer()},
+		{"Total", t.Total()},
+	} {
+		value = value + fmt.Sprintf("%-20s %s\n", Cyan(item.Label+":"), formatDuration(item.Duration))
+	}
+
+	value = value + fmt.Sprintf(
+		"%-20s reused=%t idle=%t (%s) remote=%s local=%s\n",
+		Cyan("Connection:"),
+		t.Reused,
+		t.WasIdle,
+		t.IdleTime,
+		t.RemoteAddr,
+		t.LocalAddr,
+	)
+
+	return value
+}
+
+// timedBody marks the end of the content transfer once the body is consumed
+type timedBody struct {
+	io.ReadCloser
+	timing *Timing
+}
+
+// Read reads from the body and marks the end on EOF
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	if err == io.EOF {
+		b.timing.Finish()
+	}
+
+	return n, err
+}
+
+// Close closes the body
+func (b *timedBody) Close() error {
+	b.timing.Finish()
+	return b.ReadCloser.Close()
+}
+
+// between returns the duration between two timestamps if both are recorded
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+
+	return end.Sub(start)
+}
+
+// formatDuration formats a duration or a dash if not applicable
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clivern/poodle/pkg"
+)
+
+// TestTiming test cases
+func TestTiming(t *testing.T) {
+	t.Run("TestTiming", func(t *testing.T) {
+		srv := pkg.ServerMock("/", `{"status":"ok"}`, http.StatusOK)
+		defer srv.Close()
+
+		httpClient := NewHTTPClient()
+		caller := NewCaller(httpClient)
+
+		response, err := httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+
+		_, err = httpClient.ToString(response)
+		pkg.Expect(t, nil, err)
+
+		timing, ok := GetTiming(response)
+
+		pkg.Expect(t, true, ok)
+		pkg.Expect(t, false, timing.Reused)
+		pkg.Expect(t, srv.Listener.Addr().String(), timing.RemoteAddr)
+		pkg.Expect(t, true, timing.TCPConnection() > 0)
+		pkg.Expect(t, time.Duration(0), timing.TLSHandshake())
+		pkg.Expect(t, true, timing.TimeToFirstByte() > 0)
+		pkg.Expect(t, true, timing.Total() >= timing.TimeToFirstByte())
+
+		response, err = httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+
+		caller.Timing = true
+		output := caller.Pretty(response)
+
+		timing, _ = GetTiming(response)
+
+		pkg.Expect(t, true, timing.Reused)
+		pkg.Expect(t, time.Duration(0), timing.TCPConnection())
+		pkg.Expect(t, true, strings.Contains(output, "Time To First Byte"))
+		pkg.Expect(t, true, strings.Contains(output, "reused=true"))
+	})
+}
@@ -0,0 +1,284 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/core/util"
+
+	"golang.org/x/net/http2"
+)
+
+// socketHostSuffix marks hosts that encode a unix socket path, .invalid is a reserved TLD
+const socketHostSuffix = ".sock.invalid"
+
+const (
+	// ProtocolAuto negotiates http/2 over TLS and falls back to http/1.1
+	ProtocolAuto = "auto"
+	// ProtocolHTTP1 always speaks http/1.1
+	ProtocolHTTP1 = "http1"
+	// ProtocolH2 requires http/2 negotiated over TLS
+	ProtocolH2 = "h2"
+	// ProtocolH2C speaks http/2 over cleartext with prior knowledge
+	ProtocolH2C = "h2c"
+)
+
+// Protocols are the supported values of a service protocol
+var Protocols = []string{ProtocolAuto, ProtocolHTTP1, ProtocolH2, ProtocolH2C}
+
+// connectTimeoutKey is the context key of the connect timeout
+type connectTimeoutKey struct{}
+
+// TransportConfig struct
+type TransportConfig struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	DisableHTTP2        bool
+	DisableCompression  bool
+	// TLSClientConfig is the TLS configuration, the default one is used if not set
+	TLSClientConfig *tls.Config
+}
+
+// environmentProxy gets the proxy of a request from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables
+var environmentProxy = http.ProxyFromEnvironment
+
+var (
+	sharedConfig     = NewTransportConfig()
+	sharedTransports = map[string]http.RoundTripper{}
+	sharedLock       sync.Mutex
+)
+
+// NewTransportConfig creates a transport config with sane defaults
+func NewTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+// NewTransportConfigFromConfigs creates a transport config from poodle configs
+func NewTransportConfigFromConfigs(configs model.HTTP) (TransportConfig, error) {
+	config := NewTransportConfig()
+	config.DisableHTTP2 = configs.DisableHTTP2
+	config.DisableCompression = configs.DisableCompression
+
+	if configs.MaxIdleConns > 0 {
+		config.MaxIdleConns = configs.MaxIdleConns
+	}
+
+	if configs.MaxIdleConnsPerHost > 0 {
+		config.MaxIdleConnsPerHost = configs.MaxIdleConnsPerHost
+	}
+
+	if configs.MaxConnsPerHost > 0 {
+		config.MaxConnsPerHost = configs.MaxConnsPerHost
+	}
+
+	if configs.IdleConnTimeout != "" {
+		timeout, err := util.ParseTimeout(configs.IdleConnTimeout)
+
+		if err != nil {
+			return config, err
+		}
+
+		config.IdleConnTimeout = timeout
+	}
+
+	return config, nil
+}
+
+// NewTransport creates a http transport that dials tcp and unix sockets
+//
+// The connect timeout is taken from the request context so that
+// one transport can be shared by clients with different timeouts.
+func NewTransport(config TransportConfig) *http.Transport {
+	transport := &http.Transport{
+		Proxy:                 proxyFromEnvironment,
+		DialContext:           dialContext,
+		TLSClientConfig:       config.TLSClientConfig,
+		ForceAttemptHTTP2:     !config.DisableHTTP2,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		DisableCompression:    config.DisableCompression,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	if config.DisableHTTP2 {
+		// A non nil empty map disables the http/2 upgrade and
+		// servers must not be offered h2 over ALPN either
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		transport.TLSClientConfig = &tls.Config{}
+
+		if config.TLSClientConfig != nil {
+			transport.TLSClientConfig = config.TLSClientConfig.Clone()
+		}
+
+		transport.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	}
+
+	return transport
+}
+
+// NewProtocolTransport creates a transport that speaks the protocol
+func NewProtocolTransport(protocol string, config TransportConfig) (http.RoundTripper, error) {
+	switch protocol {
+	case "", ProtocolAuto:
+		return NewTransport(config), nil
+	case ProtocolHTTP1:
+		config.DisableHTTP2 = true
+		return NewTransport(config), nil
+	case ProtocolH2:
+		return &http2.Transport{
+			TLSClientConfig:    config.TLSClientConfig,
+			DisableCompression: config.DisableCompression,
+			DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+				conn, err := dialContext(ctx, network, addr)
+
+				if err != nil {
+					return nil, err
+				}
+
+				tlsConn := tls.Client(conn, cfg)
+
+				if err := tlsConn.HandshakeContext(ctx); err != nil {
+					conn.Close()
+					return nil, err
+				}
+
+				if tlsConn.ConnectionState().NegotiatedProtocol != http2.NextProtoTLS {
+					conn.Close()
+					return nil, fmt.Errorf("Server at %s did not negotiate h2 over ALPN", addr)
+				}
+
+				return tlsConn, nil
+			},
+		}, nil
+	case ProtocolH2C:
+		return &http2.Transport{
+			AllowHTTP:          true,
+			DisableCompression: config.DisableCompression,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialContext(ctx, network, addr)
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf(
+		"Unsupported protocol %s, use one of %s",
+		protocol,
+		strings.Join(Protocols, ", "),
+	)
+}
+
+// ConfigureTransport replaces the shared transports, it should be called before sending requests
+func ConfigureTransport(config TransportConfig) {
+	sharedLock.Lock()
+	defer sharedLock.Unlock()
+
+	for _, transport := range sharedTransports {
+		if closer, ok := transport.(interface{ CloseIdleConnections() }); ok {
+			closer.CloseIdleConnections()
+		}
+	}
+
+	sharedConfig = config
+	sharedTransports = map[string]http.RoundTripper{}
+}
+
+// ProtocolTransport gets the transport shared by all http clients speaking the protocol
+func ProtocolTransport(protocol string) (http.RoundTripper, error) {
+	if protocol == "" {
+		protocol = ProtocolAuto
+	}
+
+	sharedLock.Lock()
+	defer sharedLock.Unlock()
+
+	if transport, ok := sharedTransports[protocol]; ok {
+		return transport, nil
+	}
+
+	transport, err := NewProtocolTransport(protocol, sharedConfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sharedTransports[protocol] = transport
+
+	return transport, nil
+}
+
+// dialContext dials tcp addresses and the unix sockets encoded in hosts
+func dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		KeepAlive: 30 * time.Second,
+	}
+
+	if timeout, ok := ctx.Value(connectTimeoutKey{}).(time.Duration); ok && timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if socket, ok := DecodeSocketHost(addr); ok {
+		return dialer.DialContext(ctx, "unix", socket)
+	}
+
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// proxyFromEnvironment gets the proxy of a request, requests to unix sockets never go through a proxy
+func proxyFromEnvironment(req *http.Request) (*url.URL, error) {
+	if _, ok := DecodeSocketHost(req.URL.Host); ok {
+		return nil, nil
+	}
+
+	return environmentProxy(req)
+}
+
+// EncodeSocketHost encodes a unix socket path as a host so that connections
+// to different sockets are pooled separately
+func EncodeSocketHost(socket string) string {
+	return fmt.Sprintf("%s%s", hex.EncodeToString([]byte(socket)), socketHostSuffix)
+}
+
+// DecodeSocketHost decodes a unix socket path from a host or an address
+func DecodeSocketHost(addr string) (string, bool) {
+	host := addr
+
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+
+	if !strings.HasSuffix(host, socketHostSuffix) {
+		return "", false
+	}
+
+	socket, err := hex.DecodeString(strings.TrimSuffix(host, socketHostSuffix))
+
+	if err != nil {
+		return "", false
+	}
+
+	return string(socket), true
+}
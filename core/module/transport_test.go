@@ -0,0 +1,214 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/pkg"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// TestTransport test cases
+func TestTransport(t *testing.T) {
+	t.Run("TestSocketHost", func(t *testing.T) {
+		host := EncodeSocketHost("/var/run/docker.sock")
+
+		socket, ok := DecodeSocketHost(host)
+		pkg.Expect(t, true, ok)
+		pkg.Expect(t, "/var/run/docker.sock", socket)
+
+		socket, ok = DecodeSocketHost(fmt.Sprintf("%s:80", host))
+		pkg.Expect(t, true, ok)
+		pkg.Expect(t, "/var/run/docker.sock", socket)
+
+		_, ok = DecodeSocketHost("example.com:80")
+		pkg.Expect(t, false, ok)
+	})
+
+	t.Run("TestTransportConfig", func(t *testing.T) {
+		config, err := NewTransportConfigFromConfigs(model.HTTP{
+			MaxConnsPerHost: 4,
+			IdleConnTimeout: "5s",
+			DisableHTTP2:    true,
+		})
+
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 100, config.MaxIdleConns)
+		pkg.Expect(t, 4, config.MaxConnsPerHost)
+		pkg.Expect(t, 5*time.Second, config.IdleConnTimeout)
+
+		transport := NewTransport(config)
+		pkg.Expect(t, false, transport.ForceAttemptHTTP2)
+		pkg.Expect(t, 0, len(transport.TLSNextProto))
+		pkg.Expect(t, true, transport.TLSNextProto != nil)
+
+		_, err = NewTransportConfigFromConfigs(model.HTTP{IdleConnTimeout: "soon"})
+		pkg.Expect(t, true, err != nil)
+	})
+
+	t.Run("TestSharedConnections", func(t *testing.T) {
+		srv := pkg.ServerMock("/", `{"status":"ok"}`, http.StatusOK)
+		defer srv.Close()
+
+		reused := []bool{}
+
+		for i := 0; i < 2; i++ {
+			httpClient := NewHTTPClient()
+			response, err := httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{})
+			pkg.Expect(t, nil, err)
+
+			httpClient.DrainAndClose(response)
+
+			timing, _ := GetTiming(response)
+			reused = append(reused, timing.Reused)
+		}
+
+		pkg.Expect(t, []bool{false, true}, reused)
+	})
+
+	t.Run("TestSocketConnections", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		first, err := pkg.SocketServerMock(filepath.Join(dir, "first.sock"), "/", `first`, http.StatusOK)
+		pkg.Expect(t, nil, err)
+		defer first.Close()
+
+		second, err := pkg.SocketServerMock(filepath.Join(dir, "second.sock"), "/", `second`, http.StatusOK)
+		pkg.Expect(t, nil, err)
+		defer second.Close()
+
+		httpClient := NewHTTPClient()
+
+		for _, name := range []string{"first", "second", "first"} {
+			response, err := httpClient.Get(
+				context.TODO(),
+				fmt.Sprintf("unix://%s/", filepath.Join(dir, fmt.Sprintf("%s.sock", name))),
+				map[string]string{},
+				map[string]string{},
+			)
+			pkg.Expect(t, nil, err)
+
+			body, err := httpClient.ToString(response)
+			pkg.Expect(t, nil, err)
+			pkg.Expect(t, name, body)
+		}
+	})
+
+	t.Run("TestSocketProxy", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "poodle")
+		pkg.Expect(t, nil, err)
+		defer os.RemoveAll(dir)
+
+		socket, err := pkg.SocketServerMock(filepath.Join(dir, "api.sock"), "/", `socket`, http.StatusOK)
+		pkg.Expect(t, nil, err)
+		defer socket.Close()
+
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("proxy"))
+		}))
+		defer proxy.Close()
+
+		t.Setenv("HTTP_PROXY", proxy.URL)
+
+		// http.ProxyFromEnvironment reads the environment once per process
+		defer func(value func(*http.Request) (*url.URL, error)) {
+			environmentProxy = value
+		}(environmentProxy)
+
+		environmentProxy = func(req *http.Request) (*url.URL, error) {
+			return httpproxy.FromEnvironment().ProxyFunc()(req.URL)
+		}
+
+		httpClient := NewHTTPClient()
+		httpClient.Transport = NewTransport(NewTransportConfig())
+
+		for _, item := range [][]string{
+			{fmt.Sprintf("unix://%s/", filepath.Join(dir, "api.sock")), "socket"},
+			{"http://poodle.test/", "proxy"},
+		} {
+			response, err := httpClient.Get(context.TODO(), item[0], map[string]string{}, map[string]string{})
+			pkg.Expect(t, nil, err)
+
+			body, err := httpClient.ToString(response)
+			pkg.Expect(t, nil, err)
+			pkg.Expect(t, item[1], body)
+		}
+	})
+
+	t.Run("TestMiddlewares", func(t *testing.T) {
+		srv := pkg.ServerMock("/", `{"status":"ok"}`, http.StatusOK)
+		defer srv.Close()
+
+		calls := []string{}
+		trace := func(name string) Middleware {
+			return func(next http.RoundTripper) http.RoundTripper {
+				return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					calls = append(calls, fmt.Sprintf("%s:%s", name, req.Header.Get("Authorization")))
+					return next.RoundTrip(req)
+				})
+			}
+		}
+
+		httpClient := NewHTTPClient()
+		httpClient.Use(trace("outer"), AuthMiddleware(srv.Listener.Addr().String(), map[string]string{
+			"Authorization": "token secret",
+		}), trace("inner"))
+
+		response, err := httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+		httpClient.DrainAndClose(response)
+
+		pkg.Expect(t, []string{"outer:", "inner:token secret"}, calls)
+
+		calls = []string{}
+		httpClient.Middlewares = []Middleware{AuthMiddleware("example.com", map[string]string{
+			"Authorization": "token secret",
+		}), trace("inner")}
+
+		response, err = httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+		httpClient.DrainAndClose(response)
+
+		pkg.Expect(t, []string{"inner:"}, calls)
+	})
+
+	t.Run("TestReadTimeout", func(t *testing.T) {
+		srv := pkg.ServerMock("/", `{"status":"ok"}`, http.StatusOK)
+		defer srv.Close()
+
+		transport, err := ProtocolTransport(ProtocolAuto)
+		pkg.Expect(t, nil, err)
+
+		slow := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			select {
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			case <-time.After(time.Second):
+				return transport.RoundTrip(req)
+			}
+		})
+
+		httpClient := NewHTTPClient()
+		httpClient.Transport = slow
+		httpClient.ReadTimeout = 50 * time.Millisecond
+
+		_, err = httpClient.Get(context.TODO(), srv.URL, map[string]string{}, map[string]string{})
+		pkg.Expect(t, true, err != nil)
+	})
+}
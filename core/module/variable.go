@@ -0,0 +1,250 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/core/util"
+	. "github.com/logrusorgru/aurora/v3"
+	"github.com/manifoldco/promptui"
+)
+
+const (
+	// StringVariable is the default variable type
+	StringVariable = "string"
+	// IntVariable is an integer variable
+	IntVariable = "int"
+	// FloatVariable is a float variable
+	FloatVariable = "float"
+	// BoolVariable is a boolean variable
+	BoolVariable = "bool"
+	// JSONVariable is a variable holding any JSON value
+	JSONVariable = "json"
+)
+
+// VariableTypes are the supported variable types
+var VariableTypes = []string{StringVariable, IntVariable, FloatVariable, BoolVariable, JSONVariable}
+
+// DeclareFields attaches the service variables declarations to the fields
+func (c *Caller) DeclareFields(fields map[string]Field, service *model.Service) map[string]Field {
+	for key, field := range fields {
+		variable, ok := service.Variables[key]
+
+		if !ok {
+			continue
+		}
+
+		variable.Secret = variable.Secret || field.IsSecret()
+		field.Variable = variable
+		field.Prompt = fieldPrompt(key, field)
+		fields[key] = field
+	}
+
+	return fields
+}
+
+// CheckVariables validates the service variables declarations
+func CheckVariables(service *model.Service) error {
+	for name, variable := range service.Variables {
+		if variable.Type != "" && !util.InArray(variable.Type, VariableTypes) {
+			return fmt.Errorf(
+				"Invalid type %s of variable %s, use one of %s",
+				variable.Type,
+				name,
+				strings.Join(VariableTypes, ", "),
+			)
+		}
+
+		if variable.Pattern != "" {
+			if _, err := regexp.Compile(variable.Pattern); err != nil {
+				return fmt.Errorf("Invalid pattern of variable %s: %s", name, err.Error())
+			}
+		}
+
+		for _, item := range variable.Enum {
+			if err := validateType(variable.Type, item); err != nil {
+				return fmt.Errorf("Invalid choice %s of variable %s: %s", item, name, err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// Validator gets a validator for the field value built from its declaration
+//
+// An empty value is valid only if the field is optional.
+func (f Field) Validator() promptui.ValidateFunc {
+	return func(input string) error {
+		if IsEmpty(input) {
+			if f.IsOptional {
+				return nil
+			}
+
+			return NotEmpty(input)
+		}
+
+		return f.Validate(input)
+	}
+}
+
+// Validate validates a value against the field declaration
+func (f Field) Validate(value string) error {
+	variable := f.Variable
+
+	if err := validateType(variable.Type, value); err != nil {
+		return err
+	}
+
+	if variable.Pattern != "" {
+		m, err := regexp.Compile(variable.Pattern)
+
+		if err != nil {
+			return err
+		}
+
+		if !m.MatchString(value) {
+			return fmt.Errorf("Input must match %s", variable.Pattern)
+		}
+	}
+
+	if len(variable.Enum) > 0 && !util.InArray(value, variable.Enum) {
+		return fmt.Errorf("Input must be one of %s", strings.Join(variable.Enum, ", "))
+	}
+
+	return nil
+}
+
+// IsSecret checks if the field value is a secret
+func (f Field) IsSecret() bool {
+	return f.Variable.Secret
+}
+
+// Choices gets the enum choices of the field with the default first
+func (f Field) Choices() []string {
+	choices := []string{}
+
+	if f.IsOptional && util.InArray(f.Default, f.Variable.Enum) {
+		choices = append(choices, f.Default)
+	}
+
+	for _, item := range f.Variable.Enum {
+		if !util.InArray(item, choices) {
+			choices = append(choices, item)
+		}
+	}
+
+	return choices
+}
+
+// JSONFields gets the fields with the string values escaped to be inserted into JSON strings
+//
+// Fields without a type are strings, other types like numbers or JSON documents
+// are inserted as is. Defaults are written in the definition so they are kept.
+func JSONFields(fields map[string]Field) map[string]Field {
+	items := make(map[string]Field)
+
+	for key, field := range fields {
+		kind := field.Variable.Type
+
+		if kind == "" {
+			kind = StringVariable
+		}
+
+		if kind == StringVariable && !(field.IsOptional && field.Value == field.Default) {
+			field.Value = escapeJSON(field.Value)
+		}
+
+		items[key] = field
+	}
+
+	return items
+}
+
+// IsJSONBody checks if the body is JSON by its content type or its content
+func IsJSONBody(body string, headers map[string]string) bool {
+	for k, v := range headers {
+		if strings.EqualFold(k, "Content-Type") {
+			return strings.Contains(strings.ToLower(v), "json")
+		}
+	}
+
+	body = strings.TrimSpace(body)
+
+	return strings.HasPrefix(body, "{") || strings.HasPrefix(body, "[")
+}
+
+// validateType validates a value against the variable type
+func validateType(kind, value string) error {
+	var err error
+
+	switch kind {
+	case IntVariable:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case FloatVariable:
+		_, err = strconv.ParseFloat(value, 64)
+	case BoolVariable:
+		_, err = strconv.ParseBool(value)
+	case JSONVariable:
+		if !json.Valid([]byte(value)) {
+			err = fmt.Errorf("invalid JSON")
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("Input must be a valid %s", kind)
+	}
+
+	return nil
+}
+
+// fieldPrompt builds the prompt of a declared field
+func fieldPrompt(key string, field Field) string {
+	label := fmt.Sprintf("$%s", key)
+
+	if !field.IsOptional {
+		label = fmt.Sprintf("$%s%s", key, Red("*"))
+	}
+
+	if field.Variable.Type != "" {
+		label = fmt.Sprintf("%s [%s]", label, field.Variable.Type)
+	}
+
+	if field.Variable.Description != "" {
+		label = fmt.Sprintf("%s %s", label, field.Variable.Description)
+	}
+
+	if field.IsOptional && field.IsSecret() {
+		return fmt.Sprintf(`%s (default='%s'):`, label, Yellow(RedactedValue))
+	}
+
+	if field.IsOptional {
+		return fmt.Sprintf(`%s (default='%s'):`, label, Yellow(field.Default))
+	}
+
+	return fmt.Sprintf(`%s (default=''):`, label)
+}
+
+// escapeJSON escapes a value to be inserted into a JSON string
+func escapeJSON(value string) string {
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(value); err != nil {
+		return value
+	}
+
+	result := strings.TrimSuffix(buffer.String(), "\n")
+
+	return result[1 : len(result)-1]
+}
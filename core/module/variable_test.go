@@ -0,0 +1,141 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/pkg"
+)
+
+// variablesService gets a service with declared variables
+func variablesService(url string) *model.Service {
+	service := &model.Service{}
+	service.Main.ID = "vars"
+	service.Main.ServiceURL = url
+	service.Variables = map[string]model.Variable{
+		"limit":  {Type: IntVariable, Description: "Page size"},
+		"status": {Enum: []string{"open", "closed"}},
+		"name":   {Type: StringVariable, Pattern: `^[a-z ."]+$`},
+		"meta":   {Type: JSONVariable},
+	}
+	service.Endpoint = []model.Endpoint{{
+		ID:      "create",
+		Method:  http.MethodPost,
+		URI:     "/items",
+		Headers: [][]string{{"Content-Type", "application/json"}},
+		Body:    `{"name": "{$name}", "status": "{$status:open}", "limit": {$limit:10}, "meta": {$meta:null}, "raw": "{$raw}"}`,
+	}}
+
+	return service
+}
+
+// TestVariables test cases
+func TestVariables(t *testing.T) {
+	t.Run("TestVariablesValidate", func(t *testing.T) {
+		field := Field{Variable: model.Variable{Type: IntVariable}}
+		pkg.Expect(t, nil, field.Validate("10"))
+		pkg.Expect(t, "Input must be a valid int", field.Validate("abc").Error())
+		pkg.Expect(t, "Input must not be empty", field.Validator()("").Error())
+		pkg.Expect(t, nil, Field{IsOptional: true, Variable: field.Variable}.Validator()(""))
+
+		field = Field{Variable: model.Variable{Type: BoolVariable}}
+		pkg.Expect(t, nil, field.Validate("true"))
+		pkg.Expect(t, "Input must be a valid bool", field.Validate("yes").Error())
+
+		field = Field{Variable: model.Variable{Type: FloatVariable}}
+		pkg.Expect(t, nil, field.Validate("1.5"))
+
+		field = Field{Variable: model.Variable{Type: JSONVariable}}
+		pkg.Expect(t, nil, field.Validate(`{"a": [1]}`))
+		pkg.Expect(t, "Input must be a valid json", field.Validate(`{"a"`).Error())
+
+		field = Field{Variable: model.Variable{Pattern: `^v[0-9]+$`}}
+		pkg.Expect(t, nil, field.Validate("v2"))
+		pkg.Expect(t, "Input must match ^v[0-9]+$", field.Validate("2").Error())
+
+		field = Field{IsOptional: true, Default: "closed", Variable: model.Variable{Enum: []string{"open", "closed"}}}
+		pkg.Expect(t, "Input must be one of open, closed", field.Validate("draft").Error())
+		pkg.Expect(t, []string{"closed", "open"}, field.Choices())
+	})
+
+	t.Run("TestVariablesCheck", func(t *testing.T) {
+		service := variablesService("http://127.0.0.1")
+		pkg.Expect(t, nil, CheckVariables(service))
+
+		service.Variables["limit"] = model.Variable{Type: "number"}
+		pkg.Expect(t, "Invalid type number of variable limit, use one of string, int, float, bool, json", CheckVariables(service).Error())
+
+		service.Variables["limit"] = model.Variable{Type: IntVariable, Enum: []string{"10", "ten"}}
+		pkg.Expect(t, "Invalid choice ten of variable limit: Input must be a valid int", CheckVariables(service).Error())
+	})
+
+	t.Run("TestVariablesFields", func(t *testing.T) {
+		service := variablesService("http://127.0.0.1")
+		caller := NewCaller(NewHTTPClient())
+		fields := caller.GetFields("vars - create", service)
+
+		pkg.Expect(t, 5, len(fields))
+		pkg.Expect(t, IntVariable, fields["limit"].Variable.Type)
+		pkg.Expect(t, []string{"open", "closed"}, fields["status"].Variable.Enum)
+		pkg.Expect(t, "", fields["raw"].Variable.Type)
+
+		fields["name"] = Field{Value: "abc"}
+		fields["limit"] = Field{IsOptional: true, Default: "10", Value: "ten"}
+
+		_, _, _, err := caller.Prepare(service, service.Endpoint[0], fields)
+		pkg.Expect(t, "Invalid value of $limit: Input must be a valid int", err.Error())
+	})
+
+	t.Run("TestVariablesJSONBody", func(t *testing.T) {
+		requests := make(chan string, 1)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			requests <- string(body)
+		}))
+		defer srv.Close()
+
+		service := variablesService(srv.URL)
+		caller := NewCaller(NewHTTPClient())
+		fields := caller.GetFields("vars - create", service)
+
+		for key, field := range fields {
+			field.Value = field.Default
+			fields[key] = field
+		}
+
+		name := fields["name"]
+		name.Value = `a "quoted" name`
+		fields["name"] = name
+
+		meta := fields["meta"]
+		meta.Value = `{"tags": ["a"]}`
+		fields["meta"] = meta
+
+		raw := fields["raw"]
+		raw.Value = `a"b \n`
+		fields["raw"] = raw
+
+		response, err := caller.Call("vars - create", service, fields)
+		pkg.Expect(t, nil, err)
+		response.Body.Close()
+
+		pkg.Expect(t, `{"name": "a \"quoted\" name", "status": "open", "limit": 10, "meta": {"tags": ["a"]}, "raw": "a\"b \\n"}`, <-requests)
+
+		// Defaults are inserted as written in the definition
+		items := JSONFields(map[string]Field{
+			"tags": {IsOptional: true, Default: `["a"]`, Value: `["a"]`},
+			"note": {IsOptional: true, Default: `["a"]`, Value: `say "hi"`},
+		})
+
+		pkg.Expect(t, `["a"]`, items["tags"].Value)
+		pkg.Expect(t, `say \"hi\"`, items["note"].Value)
+	})
+}
@@ -0,0 +1,539 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clivern/poodle/core/util"
+	. "github.com/logrusorgru/aurora/v3"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocketEndpoint is the type of websocket endpoints
+const WebSocketEndpoint = "websocket"
+
+// MaxWebSocketMessage is the max size of a received message
+const MaxWebSocketMessage = 16 << 20
+
+// WebSocketMessage struct
+type WebSocketMessage struct {
+	Time   time.Time
+	Binary bool
+	Data   []byte
+}
+
+// webSocketMessage receives text and binary messages keeping their type
+var webSocketMessage = websocket.Codec{
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		message := v.(*WebSocketMessage)
+		message.Binary = payloadType == websocket.BinaryFrame
+		message.Data = data
+		return nil
+	},
+}
+
+// WebSocketConn is the client side of a websocket connection
+type WebSocketConn struct {
+	Conn   *websocket.Conn
+	closed bool
+	sync.Mutex
+}
+
+// NewWebSocketConn creates a websocket connection
+func NewWebSocketConn(conn *websocket.Conn) *WebSocketConn {
+	conn.MaxPayloadBytes = MaxWebSocketMessage
+
+	return &WebSocketConn{Conn: conn}
+}
+
+// WebSocket opens a websocket connection
+//
+// The upgrade request is built like other requests so unix sockets, the query
+// parameters and the cookie jar work the same, and the handshake is dumped if
+// a dumper is set. It is sent by the websocket library on its own connection
+// though, so the middlewares, the proxy and the redirect policy don't apply.
+// The connect timeout applies to dialing and the read timeout or else the
+// timeout to the handshake.
+func (h *HTTPClient) WebSocket(ctx context.Context, endpoint string, parameters, headers map[string]string) (*WebSocketConn, *http.Response, error) {
+	if strings.HasPrefix(endpoint, "ws://") {
+		endpoint = "http://" + strings.TrimPrefix(endpoint, "ws://")
+	} else if strings.HasPrefix(endpoint, "wss://") {
+		endpoint = "https://" + strings.TrimPrefix(endpoint, "wss://")
+	}
+
+	req, err := h.newRequest(ctx, http.MethodGet, endpoint, nil, parameters, headers)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, nil, fmt.Errorf("Unsupported websocket scheme %s, use ws or wss", req.URL.Scheme)
+	}
+
+	location := *req.URL
+	location.Scheme = strings.Replace(req.URL.Scheme, "http", "ws", 1)
+
+	// Unix socket requests carry the socket in the URL host
+	if req.Host != "" {
+		location.Host = req.Host
+	}
+
+	config, err := websocket.NewConfig(location.String(), fmt.Sprintf("%s://%s", req.URL.Scheme, location.Host))
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if h.Jar != nil {
+		for _, cookie := range h.Jar.Cookies(req.URL) {
+			req.AddCookie(cookie)
+		}
+	}
+
+	config.Header = req.Header
+
+	// The library adds the upgrade headers
+	if h.Dumper != nil {
+		h.Dumper.DumpRequest(req)
+	}
+
+	conn, err := h.dialWebSocket(ctx, req.URL)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timeout := h.ReadTimeout
+
+	if timeout <= 0 {
+		timeout = h.Timeout
+	}
+
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	recorder := &handshakeRecorder{Conn: conn, recording: true}
+	ws, err := websocket.NewClient(config, recorder)
+	recorder.recording = false
+
+	resp := recorder.response(req)
+
+	if resp != nil {
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			state := tlsConn.ConnectionState()
+			resp.TLS = &state
+		}
+
+		if h.Jar != nil {
+			h.Jar.SetCookies(req.URL, resp.Cookies())
+		}
+
+		if h.Dumper != nil {
+			h.Dumper.DumpResponse(resp)
+		}
+	}
+
+	if err != nil {
+		conn.Close()
+
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			return nil, nil, fmt.Errorf("Timeout awaiting websocket handshake after %s", timeout)
+		}
+
+		if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+			return nil, resp, fmt.Errorf("WebSocket handshake failed with status code %d", resp.StatusCode)
+		}
+
+		return nil, resp, fmt.Errorf("WebSocket handshake failed: %s", err.Error())
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return NewWebSocketConn(ws), resp, nil
+}
+
+// handshakeRecorder keeps the bytes read during the handshake to get its response
+type handshakeRecorder struct {
+	net.Conn
+	data      bytes.Buffer
+	recording bool
+}
+
+// Read reads from the connection and records the data during the handshake
+func (r *handshakeRecorder) Read(p []byte) (int, error) {
+	n, err := r.Conn.Read(p)
+
+	if r.recording {
+		r.data.Write(p[:n])
+	}
+
+	return n, err
+}
+
+// response parses the recorded handshake response, the body is cut to the recorded data
+func (r *handshakeRecorder) response(req *http.Request) *http.Response {
+	resp, err := http.ReadResponse(bufio.NewReader(&r.data), req)
+
+	if err != nil {
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return resp
+}
+
+// dialWebSocket dials the host of a websocket URL, wss URLs are dialed over TLS
+func (h *HTTPClient) dialWebSocket(ctx context.Context, u *url.URL) (net.Conn, error) {
+	addr := u.Host
+
+	if u.Port() == "" {
+		port := "80"
+
+		if u.Scheme == "https" {
+			port = "443"
+		}
+
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	if h.ConnectTimeout > 0 {
+		ctx = context.WithValue(ctx, connectTimeoutKey{}, h.ConnectTimeout)
+	}
+
+	conn, err := dialContext(ctx, "tcp", addr)
+
+	if err != nil || u.Scheme != "https" {
+		return conn, err
+	}
+
+	config := &tls.Config{}
+
+	if sharedConfig.TLSClientConfig != nil {
+		config = sharedConfig.TLSClientConfig.Clone()
+	}
+
+	if config.ServerName == "" {
+		config.ServerName = u.Hostname()
+	}
+
+	tlsConn := tls.Client(conn, config)
+
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// WriteText sends a text message
+func (w *WebSocketConn) WriteText(data string) error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.closed {
+		return fmt.Errorf("WebSocket connection is closed")
+	}
+
+	return websocket.Message.Send(w.Conn, data)
+}
+
+// ReadMessage reads the next text or binary message
+//
+// Pings are answered by the library. It returns io.EOF once
+// the server closes the connection.
+func (w *WebSocketConn) ReadMessage() (WebSocketMessage, error) {
+	message := WebSocketMessage{}
+
+	err := webSocketMessage.Receive(w.Conn, &message)
+
+	if err == io.EOF {
+		// Reply to the close frame of the server
+		w.Close()
+		return message, io.EOF
+	}
+
+	if err == websocket.ErrFrameTooLarge {
+		return message, fmt.Errorf("WebSocket message exceeds %s", util.FormatBytes(MaxWebSocketMessage))
+	}
+
+	if err != nil {
+		return message, err
+	}
+
+	message.Time = time.Now()
+
+	return message, nil
+}
+
+// Close sends a close frame and closes the connection
+func (w *WebSocketConn) Close() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	return w.Conn.Close()
+}
+
+// Pretty returns the message prefixed with its time and direction
+func (m WebSocketMessage) Pretty(direction string) string {
+	data := string(m.Data)
+
+	if m.Binary {
+		preview := m.Data
+
+		if len(preview) > 32 {
+			preview = preview[:32]
+		}
+
+		data = fmt.Sprintf("binary message (%s) %x", util.FormatBytes(int64(len(m.Data))), preview)
+	}
+
+	return fmt.Sprintf("%s %s %s", Blue(m.Time.Format("15:04:05.000")), Cyan(direction), data)
+}
+
+// WebSocketSession drives a websocket connection and prints the messages
+type WebSocketSession struct {
+	Conn   *WebSocketConn
+	Output io.Writer
+	// Timeout is the time to wait for replies in batch mode
+	Timeout time.Duration
+	sync.Mutex
+}
+
+// NewWebSocketSession creates a websocket session
+func NewWebSocketSession(conn *WebSocketConn, output io.Writer, timeout time.Duration) *WebSocketSession {
+	return &WebSocketSession{
+		Conn:    conn,
+		Output:  output,
+		Timeout: timeout,
+	}
+}
+
+// Batch sends the messages and waits for the replies, the timeout or the server to close
+//
+// It returns the number of received replies.
+func (s *WebSocketSession) Batch(ctx context.Context, messages []string, replies int) (int, error) {
+	defer s.Conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	received := s.receive(done)
+
+	for _, message := range messages {
+		if err := s.send(message); err != nil {
+			return 0, err
+		}
+	}
+
+	var timeout <-chan time.Time
+
+	if s.Timeout > 0 {
+		timer := time.NewTimer(s.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	count := 0
+
+	for count < replies {
+		select {
+		case <-ctx.Done():
+			return count, ctx.Err()
+		case <-timeout:
+			return count, fmt.Errorf("Timeout after %s, received %d of %d replies", s.Timeout, count, replies)
+		case err, ok := <-received:
+			if !ok {
+				return count, fmt.Errorf("Connection closed, received %d of %d replies", count, replies)
+			}
+
+			if err != nil {
+				return count, err
+			}
+
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Interactive sends the lines read from the input until it ends or the server closes
+//
+// A line like :2 sends the second message template and :q ends the session.
+func (s *WebSocketSession) Interactive(ctx context.Context, input io.Reader, templates []string) error {
+	defer s.Conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	received := s.receive(done)
+	lines := make(chan string)
+
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(input)
+		scanner.Buffer(make([]byte, 64*1024), MaxWebSocketMessage)
+
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err, ok := <-received:
+			if !ok {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+		case line, ok := <-lines:
+			if !ok || line == ":q" {
+				return nil
+			}
+
+			message, err := s.resolve(line, templates)
+
+			if err != nil {
+				s.print(Red(err.Error()).String())
+				continue
+			}
+
+			if err := s.send(message); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// resolve gets the message to send for an input line
+func (s *WebSocketSession) resolve(line string, templates []string) (string, error) {
+	if !strings.HasPrefix(line, ":") {
+		return line, nil
+	}
+
+	index := 0
+
+	if _, err := fmt.Sscanf(line, ":%d", &index); err != nil || fmt.Sprintf(":%d", index) != line {
+		return "", fmt.Errorf("Unknown command %s, use :<number> to send a message template or :q to quit", line)
+	}
+
+	if index < 1 || index > len(templates) {
+		return "", fmt.Errorf("Message template %d not found, %d templates defined", index, len(templates))
+	}
+
+	return templates[index-1], nil
+}
+
+// send sends a text message and prints it
+func (s *WebSocketSession) send(message string) error {
+	if err := s.Conn.WriteText(message); err != nil {
+		return err
+	}
+
+	s.print(WebSocketMessage{Time: time.Now(), Data: []byte(message)}.Pretty(">"))
+
+	return nil
+}
+
+// print writes a line to the output
+func (s *WebSocketSession) print(line string) {
+	s.Lock()
+	defer s.Unlock()
+
+	fmt.Fprintln(s.Output, line)
+}
+
+// receive prints the received messages, a nil error is sent for each message
+// and the channel is closed once the server closes the connection
+func (s *WebSocketSession) receive(done <-chan struct{}) <-chan error {
+	received := make(chan error, 1)
+
+	go func() {
+		defer close(received)
+
+		for {
+			message, err := s.Conn.ReadMessage()
+
+			if err == io.EOF {
+				return
+			}
+
+			if err != nil {
+				s.Conn.Lock()
+				closed := s.Conn.closed
+				s.Conn.Unlock()
+
+				// Reads fail once the session closes the connection
+				if !closed {
+					select {
+					case received <- err:
+					case <-done:
+					}
+				}
+
+				return
+			}
+
+			s.print(message.Pretty("<"))
+
+			select {
+			case received <- nil:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return received
+}
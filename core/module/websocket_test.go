@@ -0,0 +1,187 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package module
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clivern/poodle/core/model"
+	"github.com/clivern/poodle/pkg"
+	. "github.com/logrusorgru/aurora/v3"
+	"golang.org/x/net/websocket"
+)
+
+// TestWebSocket test cases
+func TestWebSocket(t *testing.T) {
+	t.Run("TestWebSocketMessages", func(t *testing.T) {
+		srv := pkg.WebSocketServerMock("/ws", nil)
+		defer srv.Close()
+
+		conn, response, err := NewHTTPClient().WebSocket(
+			context.TODO(),
+			strings.Replace(srv.URL, "http://", "ws://", 1)+"/ws",
+			map[string]string{},
+			map[string]string{},
+		)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, http.StatusSwitchingProtocols, response.StatusCode)
+		pkg.Expect(t, "websocket", strings.ToLower(response.Header.Get("Upgrade")))
+		defer conn.Close()
+
+		for _, size := range []int{5, 200, 70000} {
+			message := strings.Repeat("a", size)
+
+			pkg.Expect(t, nil, conn.WriteText(message))
+
+			reply, err := conn.ReadMessage()
+			pkg.Expect(t, nil, err)
+			pkg.Expect(t, false, reply.Binary)
+			pkg.Expect(t, size, len(reply.Data))
+		}
+	})
+
+	t.Run("TestWebSocketHandshakeFailure", func(t *testing.T) {
+		srv := pkg.ServerMock("/ws", `{"error":"not found"}`, http.StatusNotFound)
+		defer srv.Close()
+
+		_, response, err := NewHTTPClient().WebSocket(context.TODO(), srv.URL+"/ws", map[string]string{}, map[string]string{})
+		pkg.Expect(t, "WebSocket handshake failed with status code 404", err.Error())
+		pkg.Expect(t, http.StatusNotFound, response.StatusCode)
+
+		body, _ := ioutil.ReadAll(response.Body)
+		pkg.Expect(t, `{"error":"not found"}`, string(body))
+	})
+
+	t.Run("TestWebSocketHandshake", func(t *testing.T) {
+		srv := httptest.NewServer(websocket.Server{
+			Config:  websocket.Config{Header: http.Header{"Set-Cookie": {"session=xyz"}}},
+			Handler: func(ws *websocket.Conn) {},
+		})
+		defer srv.Close()
+
+		jar, _ := cookiejar.New(nil)
+		output := &bytes.Buffer{}
+
+		httpClient := NewHTTPClient()
+		httpClient.Jar = jar
+		httpClient.Dumper = NewDumper(output, []string{}, false)
+
+		conn, response, err := httpClient.WebSocket(context.TODO(), srv.URL+"/ws", map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+		defer conn.Close()
+
+		u, _ := url.Parse(srv.URL)
+		pkg.Expect(t, http.StatusSwitchingProtocols, response.StatusCode)
+		pkg.Expect(t, "session=xyz", jar.Cookies(u)[0].String())
+		pkg.Expect(t, true, strings.Contains(output.String(), "> GET /ws HTTP/1.1"))
+		pkg.Expect(t, true, strings.Contains(output.String(), "< HTTP/1.1 101 Switching Protocols"))
+	})
+
+	t.Run("TestCallWebSocket", func(t *testing.T) {
+		requests := make(chan http.Header, 1)
+		srv := pkg.WebSocketServerMock("/v1/feed", requests)
+		defer srv.Close()
+
+		service := &model.Service{}
+		service.Main.ID = "feed"
+		service.Main.ServiceURL = strings.Replace(srv.URL, "http://", "ws://", 1)
+		service.Main.Timeout = "2s"
+		service.Security.Scheme = "bearer"
+		service.Security.Bearer.Header = []string{"Authorization", "Bearer {$token}"}
+		service.Endpoint = []model.Endpoint{{
+			ID:       "events",
+			Type:     WebSocketEndpoint,
+			URI:      "/v1/feed",
+			Messages: []string{`{"subscribe":"{$topic}"}`, "ping"},
+		}}
+
+		jar, _ := cookiejar.New(nil)
+		u, _ := url.Parse(srv.URL)
+		jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "abc"}})
+
+		caller := NewCaller(NewHTTPClient())
+		caller.HTTPClient.Jar = jar
+		fields := caller.GetFields("feed - events", service)
+		pkg.Expect(t, 2, len(fields))
+
+		fields["token"] = Field{Value: "secret"}
+		fields["topic"] = Field{Value: "orders"}
+
+		_, err := caller.Call("feed - events", service, fields)
+		pkg.Expect(t, "Endpoint feed - events is a websocket endpoint", err.Error())
+
+		conn, _, err := caller.CallWebSocket(context.TODO(), "feed - events", service, fields)
+		pkg.Expect(t, nil, err)
+		header := <-requests
+		pkg.Expect(t, "Bearer secret", header.Get("Authorization"))
+		pkg.Expect(t, "session=abc", header.Get("Cookie"))
+
+		end, _ := caller.GetEndpoint("feed - events", service)
+		messages, err := caller.WebSocketMessages(end, fields)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, []string{`{"subscribe":"orders"}`, "ping"}, messages)
+
+		output := &bytes.Buffer{}
+		session := NewWebSocketSession(conn, output, caller.HTTPClient.Timeout)
+
+		count, err := session.Batch(context.TODO(), messages, 2)
+		pkg.Expect(t, nil, err)
+		pkg.Expect(t, 2, count)
+
+		session.Lock()
+		value := output.String()
+		session.Unlock()
+
+		pkg.Expect(t, true, strings.Contains(value, fmt.Sprintf(`%s {"subscribe":"orders"}`, Cyan("<"))))
+		pkg.Expect(t, true, strings.Contains(value, fmt.Sprintf("%s ping", Cyan("<"))))
+	})
+
+	t.Run("TestWebSocketBatchTimeout", func(t *testing.T) {
+		srv := pkg.WebSocketServerMock("/ws", nil)
+		defer srv.Close()
+
+		conn, _, err := NewHTTPClient().WebSocket(context.TODO(), srv.URL+"/ws", map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+
+		session := NewWebSocketSession(conn, &bytes.Buffer{}, 200*time.Millisecond)
+
+		count, err := session.Batch(context.TODO(), []string{"hello"}, 2)
+		pkg.Expect(t, 1, count)
+		pkg.Expect(t, "Timeout after 200ms, received 1 of 2 replies", err.Error())
+	})
+
+	t.Run("TestWebSocketInteractive", func(t *testing.T) {
+		srv := pkg.WebSocketServerMock("/ws", nil)
+		defer srv.Close()
+
+		conn, _, err := NewHTTPClient().WebSocket(context.TODO(), srv.URL+"/ws", map[string]string{}, map[string]string{})
+		pkg.Expect(t, nil, err)
+
+		output := &bytes.Buffer{}
+		session := NewWebSocketSession(conn, output, 0)
+
+		err = session.Interactive(context.TODO(), strings.NewReader("hello\n:1\n:7\n:q\nignored\n"), []string{"template"})
+		pkg.Expect(t, nil, err)
+
+		session.Lock()
+		value := output.String()
+		session.Unlock()
+
+		pkg.Expect(t, true, strings.Contains(value, fmt.Sprintf("%s hello", Cyan(">"))))
+		pkg.Expect(t, true, strings.Contains(value, fmt.Sprintf("%s template", Cyan(">"))))
+		pkg.Expect(t, true, strings.Contains(value, "Message template 7 not found"))
+		pkg.Expect(t, false, strings.Contains(value, "ignored"))
+	})
+}
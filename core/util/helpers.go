@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -43,7 +44,8 @@ func InArray(val interface{}, array interface{}) bool {
 	return false
 }
 
-// ListFiles lists all files inside a dir
+// ListFiles lists all files inside a dir and its subdirectories, hidden
+// subdirectories like .git are skipped
 func ListFiles(basePath string) (map[string]File, error) {
 	ident := ""
 	files := make(map[string]File)
@@ -54,6 +56,10 @@ func ListFiles(basePath string) (map[string]File, error) {
 			return err
 		}
 
+		if IsHiddenDir(basePath, path, info) {
+			return filepath.SkipDir
+		}
+
 		modifParsed, err := dateparse.ParseLocal(info.ModTime().String())
 
 		if err != nil {
@@ -83,6 +89,11 @@ func ListFiles(basePath string) (map[string]File, error) {
 	return files, nil
 }
 
+// IsHiddenDir checks if a walked path is a hidden subdirectory of the base path
+func IsHiddenDir(basePath, path string, info os.FileInfo) bool {
+	return info.IsDir() && path != basePath && strings.HasPrefix(info.Name(), ".")
+}
+
 // ReadFile get the file content
 func ReadFile(path string) (string, error) {
 	data, err := ioutil.ReadFile(path)
@@ -225,3 +236,48 @@ func EnsureDir(dirName string, mode int) (bool, error) {
 func DeleteFile(path string) error {
 	return os.Remove(path)
 }
+
+// FormatBytes formats a size in bytes to a human readable format
+func FormatBytes(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// ParseTimeout parses a timeout like 500ms, 30s or 2m, plain numbers are seconds
+//
+// It returns zero if the value is empty.
+func ParseTimeout(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		return 0, nil
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		value = fmt.Sprintf("%ds", seconds)
+	}
+
+	timeout, err := time.ParseDuration(value)
+
+	if err != nil {
+		return 0, fmt.Errorf("Invalid timeout %s: %s", value, err.Error())
+	}
+
+	if timeout < 0 {
+		return 0, fmt.Errorf("Invalid timeout %s: must not be negative", value)
+	}
+
+	return timeout, nil
+}
@@ -6,6 +6,7 @@ package util
 
 import (
 	"testing"
+	"time"
 
 	"github.com/clivern/poodle/pkg"
 )
@@ -21,3 +22,37 @@ func TestInArray(t *testing.T) {
 		pkg.Expect(t, InArray(9, []int{2, 3, 1}), false)
 	})
 }
+
+// TestFormatBytes test cases
+func TestFormatBytes(t *testing.T) {
+	t.Run("TestFormatBytes", func(t *testing.T) {
+		pkg.Expect(t, FormatBytes(0), "0 B")
+		pkg.Expect(t, FormatBytes(1023), "1023 B")
+		pkg.Expect(t, FormatBytes(1024), "1.0 KiB")
+		pkg.Expect(t, FormatBytes(1536), "1.5 KiB")
+		pkg.Expect(t, FormatBytes(5*1024*1024), "5.0 MiB")
+	})
+}
+
+// TestParseTimeout test cases
+func TestParseTimeout(t *testing.T) {
+	t.Run("TestParseTimeout", func(t *testing.T) {
+		for value, want := range map[string]time.Duration{
+			"":      0,
+			"30":    30 * time.Second,
+			"30s":   30 * time.Second,
+			"500ms": 500 * time.Millisecond,
+			"2m":    2 * time.Minute,
+			"1m30s": 90 * time.Second,
+		} {
+			timeout, err := ParseTimeout(value)
+			pkg.Expect(t, err, nil)
+			pkg.Expect(t, timeout, want)
+		}
+
+		for _, value := range []string{"abc", "10x", "-5s"} {
+			_, err := ParseTimeout(value)
+			pkg.Expect(t, err != nil, true)
+		}
+	})
+}
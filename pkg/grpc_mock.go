@@ -0,0 +1,178 @@
+// Copyright 2020 Clivern. All rights reserved.
+// Use of this source code is governed by the MIT
+// license that can be found in the LICENSE file.
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// EchoService is the full name of the mocked grpc service
+const EchoService = "poodle.test.Echo"
+
+// EchoFileDescriptor describes the mocked grpc service
+//
+//	service Echo {
+//	    rpc Say(EchoRequest) returns (EchoReply);
+//	    rpc Repeat(EchoRequest) returns (stream EchoReply);
+//	}
+func EchoFileDescriptor() *descriptorpb.FileDescriptorProto {
+	field := func(name string, number int32, kind descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     kind.Enum(),
+		}
+	}
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("poodle/test/echo.proto"),
+		Package: proto.String("poodle.test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("EchoRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("message", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+					field("count", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+				},
+			},
+			{
+				Name: proto.String("EchoReply"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("message", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+					field("index", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+					field("token", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("Echo"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String("Say"),
+						InputType:  proto.String(".poodle.test.EchoRequest"),
+						OutputType: proto.String(".poodle.test.EchoReply"),
+					},
+					{
+						Name:            proto.String("Repeat"),
+						InputType:       proto.String(".poodle.test.EchoRequest"),
+						OutputType:      proto.String(".poodle.test.EchoReply"),
+						ServerStreaming: proto.Bool(true),
+					},
+				},
+			},
+		},
+	}
+}
+
+// GRPCServerMock mocks grpc server with the echo service and server reflection
+//
+// Replies echo the request message and the authorization metadata as token.
+func GRPCServerMock() (*grpc.Server, string, error) {
+	fd, err := protodesc.NewFile(EchoFileDescriptor(), nil)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	files := &protoregistry.Files{}
+
+	if err := files.RegisterFile(fd); err != nil {
+		return nil, "", err
+	}
+
+	request := fd.Messages().ByName("EchoRequest")
+	reply := fd.Messages().ByName("EchoReply")
+
+	newReply := func(ctx context.Context, in *dynamicpb.Message, index int32) *dynamicpb.Message {
+		out := dynamicpb.NewMessage(reply)
+		out.Set(reply.Fields().ByName("message"), in.Get(request.Fields().ByName("message")))
+		out.Set(reply.Fields().ByName("index"), protoreflect.ValueOfInt32(index))
+
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+			out.Set(reply.Fields().ByName("token"), protoreflect.ValueOfString(md.Get("authorization")[0]))
+		}
+
+		return out
+	}
+
+	desc := grpc.ServiceDesc{
+		ServiceName: EchoService,
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "Say",
+				Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+					in := dynamicpb.NewMessage(request)
+
+					if err := dec(in); err != nil {
+						return nil, err
+					}
+
+					grpc.SetHeader(ctx, metadata.Pairs("x-echo", "say"))
+
+					return newReply(ctx, in, 0), nil
+				},
+			},
+		},
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    "Repeat",
+				ServerStreams: true,
+				Handler: func(srv interface{}, stream grpc.ServerStream) error {
+					in := dynamicpb.NewMessage(request)
+
+					if err := stream.RecvMsg(in); err != nil {
+						return err
+					}
+
+					count := in.Get(request.Fields().ByName("count")).Int()
+
+					for i := int64(0); i < count; i++ {
+						if err := stream.SendMsg(newReply(stream.Context(), in, int32(i))); err != nil {
+							return err
+						}
+					}
+
+					return nil
+				},
+			},
+		},
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	server := grpc.NewServer()
+	server.RegisterService(&desc, struct{}{})
+
+	reflectionpb.RegisterServerReflectionServer(server, reflection.NewServer(reflection.ServerOptions{
+		Services:           server,
+		DescriptorResolver: files,
+	}))
+
+	go server.Serve(listener)
+
+	return server, fmt.Sprintf("grpc://%s", listener.Addr().String()), nil
+}
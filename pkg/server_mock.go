@@ -5,8 +5,13 @@
 package pkg
 
 import (
+	"net"
 	"net/http"
 	"net/http/httptest"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/net/websocket"
 )
 
 // ServerMock mocks http server
@@ -21,3 +26,82 @@ func ServerMock(uri, response string, statusCode int) *httptest.Server {
 
 	return srv
 }
+
+// SocketServerMock mocks http server listening on a unix socket
+func SocketServerMock(socket, uri, response string, statusCode int) (*httptest.Server, error) {
+	handler := http.NewServeMux()
+	handler.HandleFunc(uri, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Query", r.URL.RawQuery)
+		w.WriteHeader(statusCode)
+		w.Write([]byte(response))
+	})
+
+	listener, err := net.Listen("unix", socket)
+
+	if err != nil {
+		return nil, err
+	}
+
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener.Close()
+	srv.Listener = listener
+	srv.Start()
+
+	return srv, nil
+}
+
+// H2CServerMock mocks http server speaking http/2 over cleartext and http/1.1
+func H2CServerMock(uri, response string, statusCode int) *httptest.Server {
+	handler := http.NewServeMux()
+	handler.HandleFunc(uri, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Proto", r.Proto)
+		w.WriteHeader(statusCode)
+		w.Write([]byte(response))
+	})
+
+	srv := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+
+	return srv
+}
+
+// H2ServerMock mocks TLS http server negotiating http/2 over ALPN
+func H2ServerMock(uri, response string, statusCode int) *httptest.Server {
+	handler := http.NewServeMux()
+	handler.HandleFunc(uri, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Proto", r.Proto)
+		w.WriteHeader(statusCode)
+		w.Write([]byte(response))
+	})
+
+	srv := httptest.NewUnstartedServer(handler)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+
+	return srv
+}
+
+// WebSocketServerMock mocks websocket server echoing messages, the upgrade request headers are sent to the channel if set
+func WebSocketServerMock(uri string, requests chan<- http.Header) *httptest.Server {
+	handler := http.NewServeMux()
+	handler.Handle(uri, websocket.Server{Handler: func(ws *websocket.Conn) {
+		if requests != nil {
+			requests <- ws.Request().Header
+		}
+
+		for {
+			var message string
+
+			if err := websocket.Message.Receive(ws, &message); err != nil {
+				return
+			}
+
+			if err := websocket.Message.Send(ws, message); err != nil {
+				return
+			}
+		}
+	}})
+
+	srv := httptest.NewServer(handler)
+
+	return srv
+}